@@ -148,6 +148,7 @@ type Parser struct {
 	cache             parsedTermCache
 	recursionDepth    int
 	maxRecursionDepth int
+	metadata          *ParserMetadata
 }
 
 type parsedTermCacheItem struct {
@@ -186,6 +187,7 @@ type ParserOptions struct {
 	// RegoVersion is the version of Rego to parse for.
 	RegoVersion        RegoVersion
 	unreleasedKeywords bool // TODO(sr): cleanup
+	collectMetadata    bool // set by (*Parser).WithCollectMetadata
 }
 
 // EffectiveRegoVersion returns the effective RegoVersion to use for parsing.
@@ -276,6 +278,19 @@ func (p *Parser) WithSkipRules(skip bool) *Parser {
 	return p
 }
 
+// WithCollectMetadata enables or disables the collection of ParserMetadata
+// while parsing. The collected metadata is available through Metadata.
+func (p *Parser) WithCollectMetadata(yes bool) *Parser {
+	p.po.collectMetadata = yes
+	return p
+}
+
+// Metadata returns the metadata collected by the last call to Parse, or nil if
+// metadata collection was not enabled.
+func (p *Parser) Metadata() *ParserMetadata {
+	return p.metadata
+}
+
 // WithJSONOptions sets the JSON options on the parser (now a no-op).
 //
 // Deprecated: Use SetOptions in the json package instead, where a longer description
@@ -459,6 +474,15 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 		}
 	}
 
+	var builtins map[string]struct{}
+	if p.po.collectMetadata {
+		p.metadata = &ParserMetadata{}
+		builtins = make(map[string]struct{}, len(p.po.Capabilities.Builtins))
+		for _, bi := range p.po.Capabilities.Builtins {
+			builtins[bi.Name] = struct{}{}
+		}
+	}
+
 	// read the first token to initialize the parser
 	p.scan()
 
@@ -475,6 +499,9 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 
 		if pkg := p.parsePackage(); pkg != nil {
 			stmts = append(stmts, pkg)
+			if p.metadata != nil {
+				p.metadata.markPackage(pkg)
+			}
 			continue
 		} else if len(p.s.errors) > 0 {
 			break
@@ -493,6 +520,9 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 			}
 
 			stmts = append(stmts, imp)
+			if p.metadata != nil {
+				p.metadata.markImport(imp)
+			}
 			continue
 		} else if len(p.s.errors) > 0 {
 			break
@@ -506,6 +536,10 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 			if rules := p.parseRules(); rules != nil {
 				for i := range rules {
 					stmts = append(stmts, rules[i])
+					if p.metadata != nil {
+						p.metadata.markRule(rules[i])
+						p.metadata.collect(rules[i], builtins)
+					}
 				}
 				continue
 			} else if len(p.s.errors) > 0 {
@@ -517,6 +551,14 @@ func (p *Parser) Parse() ([]Statement, []*Comment, Errors) {
 
 		if body := p.parseQuery(true, tokens.EOF); body != nil {
 			stmts = append(stmts, body)
+			if p.metadata != nil {
+				// Bodies like `p := 1` are turned into rules when the module
+				// is assembled, so account for them here.
+				if rule, err := ParseRuleFromBody(nil, body); err == nil {
+					p.metadata.markRule(rule)
+				}
+				p.metadata.collect(body, builtins)
+			}
 			continue
 		}
 
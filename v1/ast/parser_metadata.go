@@ -0,0 +1,365 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// RuleHeadKind classifies a rule by the shape of its head.
+type RuleHeadKind int
+
+const (
+	// CompleteRuleHead is a rule that completely defines its document.
+	CompleteRuleHead RuleHeadKind = iota
+
+	// PartialSetRuleHead is a rule that contributes elements to a set.
+	PartialSetRuleHead
+
+	// PartialObjectRuleHead is a rule that contributes key/value pairs to an object.
+	PartialObjectRuleHead
+
+	// FunctionRuleHead is a rule that takes arguments.
+	FunctionRuleHead
+)
+
+func (k RuleHeadKind) String() string {
+	switch k {
+	case CompleteRuleHead:
+		return "complete"
+	case PartialSetRuleHead:
+		return "partial set"
+	case PartialObjectRuleHead:
+		return "partial object"
+	case FunctionRuleHead:
+		return "function"
+	default:
+		return "unknown"
+	}
+}
+
+// ParserMetadata contains information gathered while parsing, which callers
+// can use without walking the resulting AST themselves. Metadata is only
+// collected when the parser is configured with WithCollectMetadata, and only
+// for statements the parser accepted.
+//
+// NOTE: Fields are ordered to minimize padding, keep it that way when adding
+// new ones.
+type ParserMetadata struct {
+	ruleHeadCounts  map[RuleHeadKind]int
+	builtins        map[string]struct{}
+	dataRefs        map[string]Ref
+	templateRefs    [][]Ref
+	imports         []*Import
+	pkgPath         Ref
+	printCalls      []*Location
+	defaultRules    int
+	arrayCompCount  int
+	setCompCount    int
+	objectCompCount int
+}
+
+// Builtins returns the sorted names of the built-in functions called.
+func (m *ParserMetadata) Builtins() []string {
+	if m == nil {
+		return nil
+	}
+	return util.KeysSorted(m.builtins)
+}
+
+// HasPrintCalls returns true if any calls to print were parsed.
+func (m *ParserMetadata) HasPrintCalls() bool {
+	return m.PrintCallCount() > 0
+}
+
+// PrintCallCount returns the number of calls to print parsed.
+func (m *ParserMetadata) PrintCallCount() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.printCalls)
+}
+
+// PrintCallLocations returns the locations of the calls to print parsed, in
+// order of appearance.
+func (m *ParserMetadata) PrintCallLocations() []*Location {
+	if m == nil {
+		return nil
+	}
+	return slices.Clone(m.printCalls)
+}
+
+// ComprehensionCounts returns the number of array, set and object
+// comprehensions parsed.
+func (m *ParserMetadata) ComprehensionCounts() (array, set, object int) {
+	if m == nil {
+		return 0, 0, 0
+	}
+	return m.arrayCompCount, m.setCompCount, m.objectCompCount
+}
+
+// DataRefs returns the distinct references to the data document, sorted.
+func (m *ParserMetadata) DataRefs() []Ref {
+	if m == nil {
+		return nil
+	}
+	refs := make([]Ref, 0, len(m.dataRefs))
+	for _, k := range util.KeysSorted(m.dataRefs) {
+		refs = append(refs, m.dataRefs[k])
+	}
+	return refs
+}
+
+// TemplateStringRefs returns, for each template string parsed, the references
+// and variables interpolated into it, in order of appearance. The operators of
+// function calls are not included, only their arguments.
+func (m *ParserMetadata) TemplateStringRefs() [][]Ref {
+	if m == nil || m.templateRefs == nil {
+		return nil
+	}
+	refs := make([][]Ref, len(m.templateRefs))
+	for i := range m.templateRefs {
+		refs[i] = slices.Clone(m.templateRefs[i])
+	}
+	return refs
+}
+
+// Imports returns the imports parsed, in order of appearance.
+func (m *ParserMetadata) Imports() []*Import {
+	if m == nil {
+		return nil
+	}
+	return slices.Clone(m.imports)
+}
+
+// HasImport returns true if an import of path, e.g., "data.foo" or
+// "future.keywords.in", was parsed. Aliases are not considered.
+func (m *ParserMetadata) HasImport(path string) bool {
+	if m == nil {
+		return false
+	}
+	for _, imp := range m.imports {
+		if imp.Path.String() == path {
+			return true
+		}
+	}
+	return false
+}
+
+// PackagePath returns the path of the first package declaration parsed, e.g.,
+// data.foo.bar, or nil if none was parsed.
+func (m *ParserMetadata) PackagePath() Ref {
+	if m == nil {
+		return nil
+	}
+	return m.pkgPath
+}
+
+// Merge adds the metadata collected in other to m, which is useful to build a
+// summary over several modules. The package path of m is kept if set.
+func (m *ParserMetadata) Merge(other *ParserMetadata) {
+	if other == nil {
+		return
+	}
+	for k, n := range other.ruleHeadCounts {
+		if m.ruleHeadCounts == nil {
+			m.ruleHeadCounts = make(map[RuleHeadKind]int, len(other.ruleHeadCounts))
+		}
+		m.ruleHeadCounts[k] += n
+	}
+	for name := range other.builtins {
+		m.markBuiltin(name)
+	}
+	for k, ref := range other.dataRefs {
+		if m.dataRefs == nil {
+			m.dataRefs = make(map[string]Ref, len(other.dataRefs))
+		}
+		m.dataRefs[k] = ref
+	}
+	m.templateRefs = append(m.templateRefs, other.templateRefs...)
+	m.imports = append(m.imports, other.imports...)
+	if m.pkgPath == nil {
+		m.pkgPath = other.pkgPath
+	}
+	m.printCalls = append(m.printCalls, other.printCalls...)
+	m.defaultRules += other.defaultRules
+	m.arrayCompCount += other.arrayCompCount
+	m.setCompCount += other.setCompCount
+	m.objectCompCount += other.objectCompCount
+}
+
+// RuleHeadCounts returns the number of parsed rules per head kind. Chained
+// rule bodies (`p { ... } { ... }`) are each counted, else branches are not.
+func (m *ParserMetadata) RuleHeadCounts() map[RuleHeadKind]int {
+	if m == nil {
+		return nil
+	}
+	return maps.Clone(m.ruleHeadCounts)
+}
+
+// RuleCount returns the number of rules parsed, counted as in RuleHeadCounts.
+func (m *ParserMetadata) RuleCount() int {
+	n := 0
+	for _, c := range m.headCounts() {
+		n += c
+	}
+	return n
+}
+
+// FunctionRuleCount returns the number of function rules parsed.
+func (m *ParserMetadata) FunctionRuleCount() int {
+	return m.headCounts()[FunctionRuleHead]
+}
+
+// DefaultRuleCount returns the number of default rules parsed. Default rules
+// are also included in RuleHeadCounts by their head kind.
+func (m *ParserMetadata) DefaultRuleCount() int {
+	if m == nil {
+		return 0
+	}
+	return m.defaultRules
+}
+
+// HasFunctions returns true if any function rules were parsed.
+func (m *ParserMetadata) HasFunctions() bool {
+	return m.FunctionRuleCount() > 0
+}
+
+// HasPartialRules returns true if any partial set or partial object rules were
+// parsed.
+func (m *ParserMetadata) HasPartialRules() bool {
+	counts := m.headCounts()
+	return counts[PartialSetRuleHead] > 0 || counts[PartialObjectRuleHead] > 0
+}
+
+// headCounts is like RuleHeadCounts but returns the map held by m.
+func (m *ParserMetadata) headCounts() map[RuleHeadKind]int {
+	if m == nil {
+		return nil
+	}
+	return m.ruleHeadCounts
+}
+
+func (m *ParserMetadata) markRule(rule *Rule) {
+	if m.ruleHeadCounts == nil {
+		m.ruleHeadCounts = make(map[RuleHeadKind]int, 4)
+	}
+	m.ruleHeadCounts[ruleHeadKind(rule)]++
+	if rule.Default {
+		m.defaultRules++
+	}
+}
+
+func (m *ParserMetadata) markPackage(pkg *Package) {
+	if m.pkgPath == nil {
+		m.pkgPath = pkg.Path
+	}
+}
+
+func (m *ParserMetadata) markImport(imp *Import) {
+	m.imports = append(m.imports, imp)
+}
+
+func (m *ParserMetadata) markBuiltin(name string) {
+	if m.builtins == nil {
+		m.builtins = map[string]struct{}{}
+	}
+	m.builtins[name] = struct{}{}
+}
+
+// collect records the calls, comprehensions and data references found in an
+// accepted statement. builtins holds the names of the built-in functions known
+// to the parser's capabilities.
+func (m *ParserMetadata) collect(stmt Statement, builtins map[string]struct{}) {
+	NewGenericVisitor(func(x any) bool {
+		switch x := x.(type) {
+		case *Expr:
+			if op := x.Operator(); op != nil {
+				m.markCall(op, x.Location, builtins)
+			}
+		case Call:
+			if op, ok := x[0].Value.(Ref); ok {
+				m.markCall(op, x[0].Location, builtins)
+			}
+		case Ref:
+			if x.HasPrefix(DefaultRootRef) {
+				if m.dataRefs == nil {
+					m.dataRefs = map[string]Ref{}
+				}
+				m.dataRefs[x.String()] = x
+			}
+		case *ArrayComprehension:
+			m.arrayCompCount++
+		case *SetComprehension:
+			m.setCompCount++
+		case *ObjectComprehension:
+			m.objectCompCount++
+		case *TemplateString:
+			m.templateRefs = append(m.templateRefs, templateStringRefs(x))
+		}
+		return false
+	}).Walk(stmt)
+}
+
+func templateStringRefs(ts *TemplateString) []Ref {
+	refs := []Ref{}
+	var vis *GenericVisitor
+	vis = NewGenericVisitor(func(x any) bool {
+		switch x := x.(type) {
+		case *Expr:
+			if x.IsCall() {
+				for _, t := range x.Operands() {
+					vis.Walk(t)
+				}
+				return true
+			}
+		case Call:
+			for _, t := range x[1:] {
+				vis.Walk(t)
+			}
+			return true
+		case Ref:
+			refs = append(refs, x)
+			return true
+		case Var:
+			refs = append(refs, Ref{NewTerm(x)})
+		}
+		return false
+	})
+	for _, part := range ts.Parts {
+		if expr, ok := part.(*Expr); ok {
+			vis.Walk(expr)
+		}
+	}
+	return refs
+}
+
+func (m *ParserMetadata) markCall(op Ref, loc *Location, builtins map[string]struct{}) {
+	name := op.String()
+	if _, ok := builtins[name]; !ok {
+		return
+	}
+	m.markBuiltin(name)
+	if name == Print.Name {
+		m.printCalls = append(m.printCalls, loc)
+	}
+}
+
+func ruleHeadKind(rule *Rule) RuleHeadKind {
+	if rule.isFunction() {
+		return FunctionRuleHead
+	}
+	switch rule.Head.DocKind() {
+	case PartialSetDoc:
+		return PartialSetRuleHead
+	case PartialObjectDoc:
+		return PartialObjectRuleHead
+	default:
+		return CompleteRuleHead
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -8960,3 +8962,347 @@ func removeCapabilityFeature(caps *Capabilities, feat string) *Capabilities {
 	caps.Features = feats
 	return caps
 }
+
+func TestParserMetadataRuleHeadCounts(t *testing.T) {
+	module := `package test
+
+p := 1
+
+s contains x if { x := 1 }
+
+o[k] := v if { k := "a"; v := 1 }
+
+f(x) := y if { y := x }
+`
+
+	parser := NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module)).
+		WithCollectMetadata(true)
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	exp := map[RuleHeadKind]int{
+		CompleteRuleHead:      1,
+		PartialSetRuleHead:    1,
+		PartialObjectRuleHead: 1,
+		FunctionRuleHead:      1,
+	}
+
+	if act := parser.Metadata().RuleHeadCounts(); !maps.Equal(exp, act) {
+		t.Fatalf("expected rule head counts %v but got %v", exp, act)
+	}
+
+	// Modifying the returned values must not affect the metadata.
+	md := parser.Metadata()
+	md.RuleHeadCounts()[CompleteRuleHead] = 10
+	if act := md.RuleHeadCounts(); !maps.Equal(exp, act) {
+		t.Fatalf("expected rule head counts %v after modification but got %v", exp, act)
+	}
+
+	parser = NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module))
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if md := parser.Metadata(); md != nil {
+		t.Fatalf("expected no metadata when collection is disabled, got %v", md)
+	}
+}
+
+func TestParserMetadataRuleCounters(t *testing.T) {
+	tests := []struct {
+		note                string
+		module              string
+		rules, funcs, defs  int
+		partials, functions bool
+	}{
+		{
+			note: "mixed",
+			module: `package test
+
+default allow := false
+
+allow if input.admin
+
+default f(_) := 0
+
+f(x) := x + 1
+
+g(x, y) := x + y
+
+s contains x if { some x in input.xs }
+`,
+			rules: 6, funcs: 3, defs: 2, partials: true, functions: true,
+		},
+		{
+			note: "complete only",
+			module: `package test
+
+p := 1
+
+q if { true }
+`,
+			rules: 2,
+		},
+		{
+			note:     "partial object",
+			module:   "package test\n\no[k] := 1 if { some k in input.keys }\n",
+			rules:    1,
+			partials: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			parser := NewParser().
+				WithFilename("test.rego").
+				WithReader(strings.NewReader(tc.module)).
+				WithCollectMetadata(true)
+
+			if _, _, errs := parser.Parse(); len(errs) > 0 {
+				t.Fatal(errs)
+			}
+
+			md := parser.Metadata()
+			if md.RuleCount() != tc.rules {
+				t.Errorf("expected %d rules but got %d", tc.rules, md.RuleCount())
+			}
+			if md.FunctionRuleCount() != tc.funcs {
+				t.Errorf("expected %d function rules but got %d", tc.funcs, md.FunctionRuleCount())
+			}
+			if md.DefaultRuleCount() != tc.defs {
+				t.Errorf("expected %d default rules but got %d", tc.defs, md.DefaultRuleCount())
+			}
+			if md.HasPartialRules() != tc.partials {
+				t.Errorf("expected HasPartialRules to be %v", tc.partials)
+			}
+			if md.HasFunctions() != tc.functions {
+				t.Errorf("expected HasFunctions to be %v", tc.functions)
+			}
+		})
+	}
+
+	var md *ParserMetadata
+	if md.RuleCount() != 0 || md.DefaultRuleCount() != 0 || md.HasFunctions() || md.HasPartialRules() {
+		t.Fatal("expected zero counters for nil metadata")
+	}
+}
+
+func TestParserMetadataCalls(t *testing.T) {
+	module := `package test
+
+import data.users
+
+allow if {
+	print("checking", input.user)
+	count(users[input.user].roles) > 0
+	data.config.enabled
+	xs := [x | some x in data.items; startswith(x, "a")]
+	ys := {y | some y in xs}
+	zs := {k: v | some k, v in data.config}
+	f(1)
+}
+
+f(x) := upper(x)
+
+log if print("done")
+`
+
+	parser := NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module)).
+		WithCollectMetadata(true)
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	md := parser.Metadata()
+
+	expBuiltins := []string{"assign", "count", "gt", "internal.member_2", "internal.member_3", "print", "startswith", "upper"}
+	if act := md.Builtins(); !slices.Equal(expBuiltins, act) {
+		t.Fatalf("expected builtins %v but got %v", expBuiltins, act)
+	}
+
+	if !md.HasPrintCalls() || md.PrintCallCount() != 2 {
+		t.Fatalf("expected 2 print calls but got %d", md.PrintCallCount())
+	}
+
+	if a, s, o := md.ComprehensionCounts(); a != 1 || s != 1 || o != 1 {
+		t.Fatalf("expected one comprehension of each kind but got %d, %d, %d", a, s, o)
+	}
+
+	var refs []string
+	for _, ref := range md.DataRefs() {
+		refs = append(refs, ref.String())
+	}
+	// Imported names are not resolved while parsing.
+	expRefs := []string{"data.config", "data.config.enabled", "data.items"}
+	if !slices.Equal(expRefs, refs) {
+		t.Fatalf("expected data refs %v but got %v", expRefs, refs)
+	}
+
+	merged := &ParserMetadata{}
+	merged.Merge(md)
+	merged.Merge(md)
+	if merged.PrintCallCount() != 4 || !slices.Equal(expBuiltins, merged.Builtins()) {
+		t.Fatalf("unexpected merged metadata: %v %v", merged.PrintCallCount(), merged.Builtins())
+	}
+	if n := merged.RuleHeadCounts()[CompleteRuleHead]; n != 4 {
+		t.Fatalf("expected 4 complete rules after merge but got %d", n)
+	}
+}
+
+func TestParserMetadataImports(t *testing.T) {
+	module := `package foo.bar
+
+import data.foo
+import future.keywords
+import input.user as u
+
+p := 1
+`
+
+	parser := NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module)).
+		WithCollectMetadata(true)
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	md := parser.Metadata()
+
+	if exp := MustParseRef("data.foo.bar"); !md.PackagePath().Equal(exp) {
+		t.Fatalf("expected package path %v but got %v", exp, md.PackagePath())
+	}
+
+	var imports []string
+	for _, imp := range md.Imports() {
+		imports = append(imports, imp.String())
+	}
+	expImports := []string{"import data.foo", "import future.keywords", "import input.user as u"}
+	if !slices.Equal(expImports, imports) {
+		t.Fatalf("expected imports %v but got %v", expImports, imports)
+	}
+
+	for path, exp := range map[string]bool{
+		"data.foo":        true,
+		"future.keywords": true,
+		"input.user":      true,
+		"u":               false,
+		"data.bar":        false,
+	} {
+		if act := md.HasImport(path); act != exp {
+			t.Errorf("expected HasImport(%q) to be %v but got %v", path, exp, act)
+		}
+	}
+
+	parser = NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module))
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if md := parser.Metadata(); md != nil || md.Imports() != nil || md.PackagePath() != nil || md.HasImport("data.foo") {
+		t.Fatalf("expected no metadata when collection is disabled, got %v", md)
+	}
+}
+
+func TestParserMetadataPrintCallLocations(t *testing.T) {
+	module := `package test
+
+allow if {
+	print("checking", input.user)
+	input.user == "admin"
+}
+
+log if {	print("done") }
+`
+
+	parser := NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module)).
+		WithCollectMetadata(true)
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	locs := parser.Metadata().PrintCallLocations()
+	exp := [][2]int{{4, 2}, {8, 10}}
+	if len(locs) != len(exp) {
+		t.Fatalf("expected %d print call locations but got %v", len(exp), locs)
+	}
+	for i, loc := range locs {
+		if loc.File != "test.rego" || loc.Row != exp[i][0] || loc.Col != exp[i][1] {
+			t.Errorf("expected print call %d at %d:%d but got %v", i, exp[i][0], exp[i][1], loc)
+		}
+	}
+
+	locs[0] = nil
+	if parser.Metadata().PrintCallLocations()[0] == nil {
+		t.Fatal("expected print call locations to be unaffected by modification")
+	}
+
+	parser = NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module))
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if locs := parser.Metadata().PrintCallLocations(); locs != nil {
+		t.Fatalf("expected no locations when collection is disabled, got %v", locs)
+	}
+}
+
+func TestParserMetadataTemplateStringRefs(t *testing.T) {
+	module := `package test
+
+greeting := $"Hi {a} {b}"
+
+message := $"{input.user.name}: {upper(c)} {1}"
+
+plain := "no template"
+`
+
+	parser := NewParser().
+		WithFilename("test.rego").
+		WithReader(strings.NewReader(module)).
+		WithCollectMetadata(true)
+
+	if _, _, errs := parser.Parse(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	var act [][]string
+	for _, refs := range parser.Metadata().TemplateStringRefs() {
+		strs := []string{}
+		for _, ref := range refs {
+			strs = append(strs, ref.String())
+		}
+		act = append(act, strs)
+	}
+
+	exp := [][]string{{"a", "b"}, {"input.user.name", "c"}}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected template string refs %v but got %v", exp, act)
+	}
+
+	refs := parser.Metadata().TemplateStringRefs()
+	refs[0][0] = MustParseRef("data.x")
+	if ref := parser.Metadata().TemplateStringRefs()[0][0]; ref.String() != "a" {
+		t.Fatalf("expected template string refs to be unaffected by modification, got %v", ref)
+	}
+}
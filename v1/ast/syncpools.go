@@ -12,6 +12,15 @@ var (
 	BytesReaderPool = util.NewSyncPool[bytes.Reader]()
 	IndexResultPool = util.NewSyncPool[IndexResult]()
 
+	// Needs custom pool because maps must be created and cleared.
+	keyCachePool = &kcPool{
+		pool: sync.Pool{
+			New: func() any {
+				return make(keyCache)
+			},
+		},
+	}
+
 	// Needs custom pool because of custom Put logic.
 	varVisitorPool = &vvPool{
 		pool: sync.Pool{
@@ -22,6 +31,42 @@ var (
 	}
 )
 
+// keyCache maps object keys to the Value used for them while converting a
+// document in InterfaceToValue. A nil keyCache caches nothing.
+type keyCache map[string]Value
+
+func (c keyCache) get(k string) Value {
+	if c == nil {
+		return String(k)
+	}
+	v, ok := c[k]
+	if !ok {
+		v = String(k)
+		if len(c) < maxKeyCacheSize {
+			c[k] = v
+		}
+	}
+	return v
+}
+
+// maxKeyCacheSize limits the number of keys cached while converting a single
+// document, so that documents with mostly unique keys do not grow the cache
+// without benefit.
+const maxKeyCacheSize = 1024
+
+type kcPool struct {
+	pool sync.Pool
+}
+
+func (p *kcPool) Get() keyCache {
+	return p.pool.Get().(keyCache)
+}
+
+func (p *kcPool) Put(c keyCache) {
+	clear(c)
+	p.pool.Put(c)
+}
+
 type vvPool struct {
 	pool sync.Pool
 }
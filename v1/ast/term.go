@@ -6,16 +6,20 @@ package ast
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"net/url"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/cespare/xxhash/v2"
@@ -57,6 +61,12 @@ type Value interface {
 
 // InterfaceToValue converts a native Go value x to a Value.
 func InterfaceToValue(x any) (Value, error) {
+	return interfaceToValue(x, nil)
+}
+
+// interfaceToValue converts x to a Value, looking up object keys in keys if
+// not nil.
+func interfaceToValue(x any, keys keyCache) (Value, error) {
 	switch x := x.(type) {
 	case Value:
 		return x, nil
@@ -76,19 +86,33 @@ func InterfaceToValue(x any) (Value, error) {
 	case uint64:
 		return InternedValueOr(x, newUint64NumberValue), nil
 	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("ast: interface conversion: unsupported number: %v", x)
+		}
 		return floatNumber(x), nil
+	case *big.Int:
+		if x == nil {
+			return NullValue, nil
+		}
+		return Number(x.String()), nil
+	case *big.Float:
+		if x == nil {
+			return NullValue, nil
+		}
+		if x.IsInf() {
+			return nil, fmt.Errorf("ast: interface conversion: unsupported number: %v", x)
+		}
+		return Number(x.Text('g', -1)), nil
 	case string:
 		return String(x), nil
 	case []any:
-		r := util.NewPtrSlice[Term](len(x))
-		for i, e := range x {
-			e, err := InterfaceToValue(e)
-			if err != nil {
-				return nil, err
-			}
-			r[i].Value = e
+		if keys == nil && hasObjectElems(x) {
+			keys = keyCachePool.Get()
+			v, err := arrayToValue(x, keys)
+			keyCachePool.Put(keys)
+			return v, err
 		}
-		return NewArray(r...), nil
+		return arrayToValue(x, keys)
 	case []string:
 		r := util.NewPtrSlice[Term](len(x))
 		for i, e := range x {
@@ -96,35 +120,173 @@ func InterfaceToValue(x any) (Value, error) {
 		}
 		return NewArray(r...), nil
 	case map[string]any:
-		kvs := util.NewPtrSlice[Term](len(x) * 2)
-		idx := 0
-		for k, v := range x {
-			kvs[idx].Value = String(k)
-			v, err := InterfaceToValue(v)
-			if err != nil {
-				return nil, err
-			}
-			kvs[idx+1].Value = v
-			idx += 2
-		}
-		tuples := make([][2]*Term, len(kvs)/2)
-		for i := 0; i < len(kvs); i += 2 {
-			tuples[i/2] = *(*[2]*Term)(kvs[i : i+2])
+		if keys == nil && hasObjectValues(x) {
+			keys = keyCachePool.Get()
+			v, err := objectToValue(x, keys)
+			keyCachePool.Put(keys)
+			return v, err
 		}
-		return NewObject(tuples...), nil
+		return objectToValue(x, keys)
 	case map[string]string:
 		r := newobject(len(x))
 		for k, v := range x {
 			r.Insert(StringTerm(k), StringTerm(v))
 		}
 		return r, nil
+	case json.RawMessage:
+		// Like json.Marshal, encode a nil or empty message as null.
+		if len(x) == 0 {
+			return NullValue, nil
+		}
+		// Decode once, instead of re-encoding the message in the round trip
+		// below. The decoded value holds no raw messages.
+		var v any
+		if err := util.UnmarshalJSON(x, &v); err != nil {
+			return nil, fmt.Errorf("ast: interface conversion: %w", err)
+		}
+		return interfaceToValue(v, keys)
+	case time.Time:
+		return String(x.Format(time.RFC3339Nano)), nil
 	default:
+		if v, ok, err := textMarshalerToValue(x); ok {
+			return v, err
+		}
 		ptr := util.Reference(x)
 		if err := util.RoundTrip(ptr); err != nil {
 			return nil, fmt.Errorf("ast: interface conversion: %w", err)
 		}
-		return InterfaceToValue(*ptr)
+		return interfaceToValue(*ptr, keys)
+	}
+}
+
+// hasObjectElems reports whether x holds more than one object. Objects in a
+// document commonly repeat their keys, so converting x shares a key cache
+// across the objects below it. Smaller inputs are converted without one.
+func hasObjectElems(x []any) bool {
+	n := 0
+	for _, e := range x {
+		if _, ok := e.(map[string]any); ok {
+			if n++; n > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasObjectValues is like hasObjectElems, for the values of an object.
+func hasObjectValues(x map[string]any) bool {
+	n := 0
+	for _, e := range x {
+		if _, ok := e.(map[string]any); ok {
+			if n++; n > 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func objectToValue(x map[string]any, keys keyCache) (Value, error) {
+	kvs := util.NewPtrSlice[Term](len(x) * 2)
+	idx := 0
+	for k, v := range x {
+		kvs[idx].Value = keys.get(k)
+		v, err := interfaceToValue(v, keys)
+		if err != nil {
+			return nil, err
+		}
+		kvs[idx+1].Value = v
+		idx += 2
 	}
+	tuples := make([][2]*Term, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		tuples[i/2] = *(*[2]*Term)(kvs[i : i+2])
+	}
+	return NewObject(tuples...), nil
+}
+
+func arrayToValue(x []any, keys keyCache) (Value, error) {
+	r := util.NewPtrSlice[Term](len(x))
+	for i, e := range x {
+		e, err := interfaceToValue(e, keys)
+		if err != nil {
+			return nil, err
+		}
+		r[i].Value = e
+	}
+	return NewArray(r...), nil
+}
+
+// InterfaceToValueInto converts the elements of src and appends them to dst.
+// Callers can preallocate dst with NewArrayWithCapacity, and convert a large
+// slice in parts, releasing each part once converted. If an element cannot be
+// converted, the error is returned and dst holds the elements converted
+// before it.
+func InterfaceToValueInto(dst *Array, src []any) error {
+	for _, x := range src {
+		v, err := InterfaceToValue(x)
+		if err != nil {
+			return err
+		}
+		dst.append(NewTerm(v))
+	}
+	return nil
+}
+
+// InterfaceToValueIntoObject converts the values of src and inserts them into
+// dst under their keys. Like InterfaceToValueInto, it allows building an
+// object incrementally. If a value cannot be converted, the error is returned
+// and dst holds some of the other entries of src.
+func InterfaceToValueIntoObject(dst Object, src map[string]any) error {
+	for k, x := range src {
+		v, err := InterfaceToValue(x)
+		if err != nil {
+			return err
+		}
+		dst.Insert(StringTerm(k), NewTerm(v))
+	}
+	return nil
+}
+
+// StreamInterfaceToValue returns an array of the converted values returned by
+// next, which is called until it returns false. Unlike converting a []any,
+// only the resulting array has to be held in memory, not the source values.
+func StreamInterfaceToValue(next func() (any, bool)) (Value, error) {
+	arr := NewArrayWithCapacity(0)
+	for {
+		x, ok := next()
+		if !ok {
+			return arr, nil
+		}
+		v, err := InterfaceToValue(x)
+		if err != nil {
+			return nil, err
+		}
+		arr.append(NewTerm(v))
+	}
+}
+
+// textMarshalerToValue converts x to a String using MarshalText if x implements
+// encoding.TextMarshaler but not json.Marshaler, which is how encoding/json
+// would encode it. Nil pointers are converted to null. It returns false for
+// other values.
+func textMarshalerToValue(x any) (Value, bool, error) {
+	tm, ok := x.(encoding.TextMarshaler)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, ok := x.(json.Marshaler); ok {
+		return nil, false, nil
+	}
+	if rv := reflect.ValueOf(x); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return NullValue, true, nil
+	}
+	bs, err := tm.MarshalText()
+	if err != nil {
+		return nil, true, fmt.Errorf("ast: interface conversion: %w", err)
+	}
+	return String(bs), true, nil
 }
 
 // ValueFromReader returns an AST value from a JSON serialized value in the reader.
@@ -1646,6 +1808,15 @@ func (arr *Array) Append(v *Term) *Array {
 	return &cpy
 }
 
+// append adds v to the end of arr in place.
+func (arr *Array) append(v *Term) {
+	h := v.Value.Hash()
+	arr.elems = append(arr.elems, v)
+	arr.hashs = append(arr.hashs, h)
+	arr.hash += h
+	arr.ground = arr.ground && v.IsGround()
+}
+
 // Set represents a set as defined by the language.
 type Set interface {
 	Value
@@ -863,6 +863,90 @@ func BenchmarkInterfaceToValueInt(b *testing.B) {
 	})
 }
 
+func BenchmarkInterfaceToValueRepeatedKeys(b *testing.B) {
+	// Small inputs have no repeated keys to share, and must not pay for the
+	// key cache.
+	small := map[string]any{
+		"array":  []any{"a", "b"},
+		"object": map[string]any{"method": "GET", "path": []any{"v1", "data"}, "user": "alice", "admin": false},
+	}
+	for name, doc := range small {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := InterfaceToValue(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	for _, n := range []int{1, 10, 1000} {
+		users := make([]any, n)
+		for i := range users {
+			users[i] = map[string]any{
+				"id": i,
+				"profile": map[string]any{
+					"name": "user",
+					"settings": map[string]any{
+						"theme":         "dark",
+						"notifications": map[string]any{"email": true, "sms": false},
+					},
+				},
+			}
+		}
+		doc := map[string]any{"users": users}
+
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := InterfaceToValue(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkStreamInterfaceToValue(b *testing.B) {
+	const n = 100_000
+
+	elem := func(i int) any {
+		return map[string]any{"id": i, "name": "user"}
+	}
+
+	// Builds the whole []any before converting it, holding both at once.
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			src := make([]any, n)
+			for i := range src {
+				src[i] = elem(i)
+			}
+			if _, err := InterfaceToValue(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Converts each element as it is produced.
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			i := 0
+			if _, err := StreamInterfaceToValue(func() (any, bool) {
+				if i == n {
+					return nil, false
+				}
+				i++
+				return elem(i - 1), true
+			}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 func BenchmarkValueToInterfaceInt(b *testing.B) {
 	term := MustParseTerm(`{
 		"foo": [1, "two", true, false, null, {3: 4}],
@@ -9,13 +9,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"net"
 	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/open-policy-agent/opa/v1/util"
@@ -124,6 +130,352 @@ func TestInterfaceToValueStructs(t *testing.T) {
 	}
 }
 
+type embeddedMeta struct {
+	ID      string `json:"id"`
+	Version int    `json:"version,omitempty"`
+}
+
+func TestInterfaceToValueStructTags(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type user struct {
+		embeddedMeta
+		Name     string            `json:"name"`
+		Nickname string            `json:"nickname,omitempty"`
+		Admin    bool              `json:"-"`
+		Address  address           `json:"address"`
+		Previous *address          `json:"previous"`
+		Manager  *user             `json:"manager,omitempty"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Untagged int
+		secret   string
+	}
+
+	tests := []struct {
+		note string
+		x    any
+		exp  string
+	}{
+		{
+			note: "tags and omitempty",
+			x:    user{embeddedMeta: embeddedMeta{ID: "u1"}, Name: "alice", Admin: true, Address: address{City: "Oslo"}, secret: "x"},
+			exp:  `{"id": "u1", "name": "alice", "address": {"city": "Oslo"}, "previous": null, "Untagged": 0}`,
+		},
+		{
+			note: "pointers and nesting",
+			x: &user{
+				embeddedMeta: embeddedMeta{ID: "u2", Version: 3},
+				Name:         "bob",
+				Previous:     &address{City: "Bergen", Zip: "5003"},
+				Manager:      &user{Name: "carol"},
+				Labels:       map[string]string{"team": "a"},
+			},
+			exp: `{
+				"id": "u2", "version": 3, "name": "bob", "address": {"city": ""},
+				"previous": {"city": "Bergen", "zip": "5003"},
+				"manager": {"id": "", "name": "carol", "address": {"city": ""}, "previous": null, "Untagged": 0},
+				"labels": {"team": "a"}, "Untagged": 0
+			}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			act, err := InterfaceToValue(tc.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := MustParseTerm(tc.exp).Value; act.Compare(exp) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestInterfaceToValueBigNumbers(t *testing.T) {
+	bi, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !ok {
+		t.Fatal("invalid big.Int literal")
+	}
+
+	bf, _, err := big.ParseFloat("3.14159265358979323846264338327950288", 10, 200, big.ToNearestEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		note string
+		x    any
+		exp  Value
+	}{
+		{"big.Int beyond int64", bi, Number("123456789012345678901234567890")},
+		{"big.Float", bf, Number(bf.Text('g', -1))},
+		{"nil big.Int", (*big.Int)(nil), NullValue},
+		{"nil big.Float", (*big.Float)(nil), NullValue},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			act, err := InterfaceToValue(tc.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act.Compare(tc.exp) != 0 {
+				t.Fatalf("expected %v but got %v", tc.exp, act)
+			}
+		})
+	}
+
+	if n, _ := bf.Float64(); Number(bf.Text('g', -1)) == floatNumber(n) {
+		t.Fatal("expected big.Float conversion to preserve more precision than float64")
+	}
+}
+
+func TestInterfaceToValueSharesKeys(t *testing.T) {
+	// Keys are cloned, as they would be when decoding JSON, so that only the
+	// key cache can make converted objects share them.
+	obj := func() map[string]any {
+		return map[string]any{strings.Clone("name"): "x", strings.Clone("id"): 1}
+	}
+
+	tests := []struct {
+		note string
+		doc  any
+		objs func(Value) []Object
+	}{
+		{
+			note: "map of maps",
+			doc:  map[string]any{"a": obj(), "b": obj()},
+			objs: func(v Value) []Object {
+				return []Object{v.(Object).Get(InternedTerm("a")).Value.(Object), v.(Object).Get(InternedTerm("b")).Value.(Object)}
+			},
+		},
+		{
+			note: "array not starting with an object",
+			doc:  []any{"x", obj(), obj()},
+			objs: func(v Value) []Object {
+				return []Object{v.(*Array).Elem(1).Value.(Object), v.(*Array).Elem(2).Value.(Object)}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			v, err := InterfaceToValue(tc.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var data []*byte
+			for _, obj := range tc.objs(v) {
+				for _, k := range obj.Keys() {
+					if k.Value.Compare(String("name")) == 0 {
+						data = append(data, unsafe.StringData(string(k.Value.(String))))
+					}
+				}
+			}
+			if len(data) != 2 || data[0] != data[1] {
+				t.Fatalf("expected objects to share the key")
+			}
+		})
+	}
+}
+
+func TestInterfaceToValueRawMessage(t *testing.T) {
+	tests := []struct {
+		note string
+		raw  string
+	}{
+		{"object", `{"a": [1, 2.5], "b": {"c": null}}`},
+		{"array", `[1, "x", true, {"y": 12345678901234567890}]`},
+		{"scalar", `3.14159265358979323846`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			exp, err := ValueFromReader(strings.NewReader(tc.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := InterfaceToValue(json.RawMessage(tc.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act.Compare(exp) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+
+			// Nested raw messages are converted as well.
+			act, err = InterfaceToValue(map[string]any{"x": json.RawMessage(tc.raw)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nested := act.(Object).Get(InternedTerm("x")); nested == nil || nested.Value.Compare(exp) != 0 {
+				t.Fatalf("expected nested %v but got %v", exp, act)
+			}
+		})
+	}
+
+	if _, err := InterfaceToValue(json.RawMessage(`{"a":`)); err == nil {
+		t.Fatal("expected error for malformed raw message")
+	}
+
+	// Nil and empty messages are encoded as null by json.Marshal.
+	for _, raw := range []json.RawMessage{nil, {}} {
+		act, err := InterfaceToValue(raw)
+		if err != nil || act.Compare(NullValue) != 0 {
+			t.Fatalf("expected null but got %v, %v", act, err)
+		}
+
+		act, err = InterfaceToValue(map[string]any{"x": raw})
+		if err != nil || act.Compare(MustParseTerm(`{"x": null}`).Value) != 0 {
+			t.Fatalf("expected nested null but got %v, %v", act, err)
+		}
+	}
+}
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) {
+	if l < 0 {
+		return nil, errors.New("negative level")
+	}
+	return []byte("level-" + strconv.Itoa(int(l))), nil
+}
+
+func TestInterfaceToValueTextMarshalers(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+	tests := []struct {
+		note string
+		x    any
+		exp  string
+	}{
+		{"time", ts, `"2024-03-01T12:30:00.0000005Z"`},
+		{"text marshaler", level(3), `"level-3"`},
+		{"nil text marshaler", (*level)(nil), `null`},
+		{"ip", net.IPv4(10, 0, 0, 1), `"10.0.0.1"`},
+		{"struct", struct {
+			Created time.Time `json:"created"`
+			Level   level     `json:"level"`
+		}{ts, 2}, `{"created": "2024-03-01T12:30:00.0000005Z", "level": "level-2"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			act, err := InterfaceToValue(tc.x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := MustParseTerm(tc.exp).Value; act.Compare(exp) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+
+	if _, err := InterfaceToValue(level(-1)); err == nil || !strings.Contains(err.Error(), "negative level") {
+		t.Fatalf("expected MarshalText error but got: %v", err)
+	}
+}
+
+func TestInterfaceToValueIncremental(t *testing.T) {
+	src := []any{1, "a", map[string]any{"b": []any{true, nil}}, 2.5}
+	exp := MustInterfaceToValue(src)
+
+	arr := NewArrayWithCapacity(len(src))
+	for i := 0; i < len(src); i += 3 {
+		if err := InterfaceToValueInto(arr, src[i:min(i+3, len(src))]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if arr.Compare(exp) != 0 || arr.Hash() != exp.Hash() {
+		t.Fatalf("expected %v but got %v", exp, arr)
+	}
+
+	i := 0
+	streamed, err := StreamInterfaceToValue(func() (any, bool) {
+		if i == len(src) {
+			return nil, false
+		}
+		i++
+		return src[i-1], true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed.Compare(exp) != 0 || streamed.Hash() != exp.Hash() {
+		t.Fatalf("expected %v but got %v", exp, streamed)
+	}
+
+	obj := NewObject()
+	if err := InterfaceToValueIntoObject(obj, map[string]any{"x": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := InterfaceToValueIntoObject(obj, map[string]any{"y": []any{"z"}}); err != nil {
+		t.Fatal(err)
+	}
+	if exp := MustParseTerm(`{"x": 1, "y": ["z"]}`).Value; obj.Compare(exp) != 0 || obj.Hash() != exp.Hash() {
+		t.Fatalf("expected %v but got %v", exp, obj)
+	}
+
+	arr = NewArrayWithCapacity(0)
+	if err := InterfaceToValueInto(arr, []any{1, math.NaN(), 2}); err == nil {
+		t.Fatal("expected error for NaN")
+	}
+	if arr.Len() != 1 {
+		t.Fatalf("expected elements before the error to be kept, got %v", arr)
+	}
+}
+
+func TestInterfaceToValueNonFiniteFloats(t *testing.T) {
+	for _, x := range []any{math.NaN(), math.Inf(1), math.Inf(-1), new(big.Float).SetInf(false)} {
+		_, err := InterfaceToValue(x)
+		if err == nil || !strings.HasPrefix(err.Error(), "ast: interface conversion: unsupported number") {
+			t.Fatalf("expected unsupported number error for %v but got: %v", x, err)
+		}
+	}
+
+	_, err := InterfaceToValue(map[string]any{"x": []any{math.NaN()}})
+	if err == nil {
+		t.Fatal("expected error for nested NaN")
+	}
+}
+
+func TestInterfaceToValueObjectsDeterministic(t *testing.T) {
+	m := map[string]any{}
+	for i := range 100 {
+		m[fmt.Sprintf("key%d", i)] = map[string]any{"n": i, "s": fmt.Sprint(i)}
+	}
+
+	first := MustInterfaceToValue(m)
+	firstJSON, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Go randomizes map iteration order, so repeated conversions insert keys
+	// in different orders. Objects sort their keys before iterating.
+	for range 20 {
+		v := MustInterfaceToValue(m)
+		bs, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(firstJSON, bs) {
+			t.Fatalf("expected identical JSON:\n%s\n%s", firstJSON, bs)
+		}
+		if v.String() != first.String() {
+			t.Fatalf("expected identical strings:\n%v\n%v", first, v)
+		}
+		if v.Hash() != first.Hash() {
+			t.Fatalf("expected identical hashes: %d != %d", first.Hash(), v.Hash())
+		}
+	}
+}
+
 type brokenMarshaller struct{}
 
 func (brokenMarshaller) MarshalJSON() ([]byte, error) {
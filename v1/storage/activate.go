@@ -0,0 +1,47 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+)
+
+// ActivateBundle replaces the document at dataPath with data and the set of
+// policies in the store with policies, within txn. Missing parent objects of
+// dataPath are created, and any existing document at dataPath is replaced
+// rather than merged, so no data from a previously activated bundle is left
+// behind under dataPath. Policies that are not in policies are deleted.
+//
+// All changes are made in txn; callers commit or abort it to activate the
+// bundle atomically.
+func ActivateBundle(ctx context.Context, store Store, txn Transaction, dataPath Path, data any, policies map[string][]byte) error {
+	if len(dataPath) > 0 {
+		if err := MakeDir(ctx, store, txn, dataPath[:len(dataPath)-1]); err != nil {
+			return err
+		}
+	}
+	if err := store.Write(ctx, txn, AddOp, dataPath, data); err != nil {
+		return err
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := policies[id]; ok {
+			continue
+		}
+		if err := store.DeletePolicy(ctx, txn, id); err != nil {
+			return err
+		}
+	}
+	for id, bs := range policies {
+		if err := store.UpsertPolicy(ctx, txn, id, bs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
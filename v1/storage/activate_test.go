@@ -0,0 +1,64 @@
+package storage_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestActivateBundle(t *testing.T) {
+	ctx := t.Context()
+	store := inmem.NewFromReader(strings.NewReader(`{"other": {"keep": true}}`))
+	path := storage.MustParsePath("/bundles/authz")
+
+	activate := func(data string, policies map[string][]byte) {
+		t.Helper()
+		if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+			return storage.ActivateBundle(ctx, store, txn, path, util.MustUnmarshalJSON([]byte(data)), policies)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	activate(`{"users": {"alice": 1}, "old": true}`, map[string][]byte{
+		"a.rego": []byte("package a"),
+		"b.rego": []byte("package b"),
+	})
+	activate(`{"users": {"bob": 2}}`, map[string][]byte{
+		"b.rego": []byte("package b.v2"),
+		"c.rego": []byte("package c"),
+	})
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	act, err := store.Read(ctx, txn, storage.RootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := util.MustUnmarshalJSON([]byte(`{"other": {"keep": true}, "bundles": {"authz": {"users": {"bob": 2}}}}`))
+	if ast.MustInterfaceToValue(act).Compare(ast.MustInterfaceToValue(exp)) != 0 {
+		t.Fatalf("expected %v but got %v", exp, act)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+	if exp := []string{"b.rego", "c.rego"}; !slices.Equal(exp, ids) {
+		t.Fatalf("expected policies %v but got %v", exp, ids)
+	}
+	bs, err := store.GetPolicy(ctx, txn, "b.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "package b.v2" {
+		t.Fatalf("expected updated policy but got %q", bs)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// Test verifies that the document at path equals expected without modifying
+// the store, mirroring the JSON Patch "test" operation. If the values differ,
+// an error with the TestFailedErr code is returned. If the path does not
+// exist, the NotFoundErr from the store is returned.
+//
+// Values are compared structurally, with numbers compared by value, so that
+// 1, 1.0 and json.Number("1") are all considered equal.
+func Test(ctx context.Context, store Store, txn Transaction, path Path, expected any) error {
+	actual, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return err
+	}
+	eq, err := valueEqual(actual, expected)
+	if err != nil {
+		return err
+	}
+	if !eq {
+		return testFailedError(path)
+	}
+	return nil
+}
+
+// DeleteIf removes the document at path within txn if it equals expected,
+// using the same comparison as Test. It returns true if the document was
+// removed, and false without error if the document differs or does not exist.
+func DeleteIf(ctx context.Context, store Store, txn Transaction, path Path, expected any) (bool, error) {
+	if err := Test(ctx, store, txn, path, expected); err != nil {
+		if IsTestFailed(err) || IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if err := store.Write(ctx, txn, RemoveOp, path, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WriteIfAbsent writes value at path within txn only if no document exists at
+// path. It returns true if the document was created, and false without
+// modifying the store if one already exists. The parent of path must exist.
+//
+// The existence check and the write happen in txn, so for stores that
+// serialize write transactions, no other writer can create the document in
+// between.
+func WriteIfAbsent(ctx context.Context, store Store, txn Transaction, path Path, value any) (bool, error) {
+	if _, err := store.Read(ctx, txn, path); err == nil {
+		return false, nil
+	} else if !IsNotFound(err) {
+		return false, err
+	}
+	if err := store.Write(ctx, txn, AddOp, path, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Increment adds delta to the integer stored at path and writes the result back
+// within txn, returning the new value. If path does not exist, the counter is
+// created starting from zero; its parent must exist. An error with the
+// InvalidPatchErr code is returned if the existing value is not an integer or
+// the result would overflow.
+func Increment(ctx context.Context, store Store, txn Transaction, path Path, delta int64) (int64, error) {
+	op := PatchOp(ReplaceOp)
+
+	current, err := store.Read(ctx, txn, path)
+	if err != nil {
+		if !IsNotFound(err) {
+			return 0, err
+		}
+		op, current = AddOp, int64(0)
+	}
+
+	n, ok := toInt64(current)
+	if !ok {
+		return 0, &Error{Code: InvalidPatchErr, Message: path.String() + ": value is not an integer"}
+	}
+
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, &Error{Code: InvalidPatchErr, Message: path.String() + ": integer overflow"}
+	}
+	n += delta
+
+	if err := store.Write(ctx, txn, op, path, json.Number(strconv.FormatInt(n, 10))); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case ast.Number:
+		return v.Int64()
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// valueEqual compares two documents, which may be native Go values or AST
+// values, using the AST comparison rules.
+func valueEqual(a, b any) (bool, error) {
+	av, err := ast.InterfaceToValue(a)
+	if err != nil {
+		return false, err
+	}
+	bv, err := ast.InterfaceToValue(b)
+	if err != nil {
+		return false, err
+	}
+	return av.Compare(bv) == 0, nil
+}
@@ -0,0 +1,204 @@
+package storage_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestTest(t *testing.T) {
+	store := inmem.NewFromReader(strings.NewReader(`{"a": {"b": 1, "c": [1, "x"]}}`))
+
+	tests := []struct {
+		note     string
+		path     string
+		expected any
+		check    func(error) bool
+	}{
+		{
+			note:     "equal object",
+			path:     "/a",
+			expected: map[string]any{"b": 1, "c": []any{1.0, "x"}},
+			check:    func(err error) bool { return err == nil },
+		},
+		{
+			note:     "numeric equality",
+			path:     "/a/b",
+			expected: 1.0,
+			check:    func(err error) bool { return err == nil },
+		},
+		{
+			note:     "mismatch",
+			path:     "/a/b",
+			expected: 2,
+			check:    storage.IsTestFailed,
+		},
+		{
+			note:     "type mismatch",
+			path:     "/a/c",
+			expected: "x",
+			check:    storage.IsTestFailed,
+		},
+		{
+			note:     "missing path",
+			path:     "/a/d",
+			expected: nil,
+			check:    storage.IsNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := t.Context()
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			err := storage.Test(ctx, store, txn, storage.MustParsePath(tc.path), tc.expected)
+			if !tc.check(err) {
+				t.Fatalf("unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"cache": {"a": "v1", "b": "v2"}}`), inmem.OptReturnASTValuesOnRead(astValues))
+
+			tests := []struct {
+				path     string
+				expected any
+				deleted  bool
+			}{
+				{path: "/cache/a", expected: "v1", deleted: true},
+				{path: "/cache/b", expected: "stale", deleted: false},
+				{path: "/cache/c", expected: "v3", deleted: false},
+			}
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				for _, tc := range tests {
+					deleted, err := storage.DeleteIf(ctx, store, txn, storage.MustParsePath(tc.path), tc.expected)
+					if err != nil {
+						return err
+					}
+					if deleted != tc.deleted {
+						t.Errorf("%s: expected deleted=%v but got %v", tc.path, tc.deleted, deleted)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/cache/a")); !storage.IsNotFound(err) {
+				t.Fatalf("expected /cache/a to be deleted but got %v", err)
+			}
+			if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/cache/b")); err != nil {
+				t.Fatalf("expected /cache/b to be intact but got %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteIfAbsent(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"counters": {"hits": 41}}`), inmem.OptReturnASTValuesOnRead(astValues))
+
+			tests := []struct {
+				path    string
+				created bool
+			}{
+				{path: "/counters/misses", created: true},
+				{path: "/counters/hits", created: false},
+			}
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				for _, tc := range tests {
+					created, err := storage.WriteIfAbsent(ctx, store, txn, storage.MustParsePath(tc.path), 0)
+					if err != nil {
+						return err
+					}
+					if created != tc.created {
+						t.Errorf("%s: expected created=%v but got %v", tc.path, tc.created, created)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := storage.ReadOne(ctx, store, storage.MustParsePath("/counters"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := util.MustUnmarshalJSON([]byte(`{"hits": 41, "misses": 0}`))
+			if ast.MustInterfaceToValue(act).Compare(ast.MustInterfaceToValue(exp)) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"counters": {"hits": 41, "name": "x", "ratio": 1.5}}`), inmem.OptReturnASTValuesOnRead(astValues))
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				n, err := storage.Increment(ctx, store, txn, storage.MustParsePath("/counters/hits"), 1)
+				if err != nil {
+					return err
+				}
+				if n != 42 {
+					t.Fatalf("expected 42 but got %d", n)
+				}
+
+				n, err = storage.Increment(ctx, store, txn, storage.MustParsePath("/counters/misses"), -3)
+				if err != nil {
+					return err
+				}
+				if n != -3 {
+					t.Fatalf("expected -3 but got %d", n)
+				}
+
+				for _, path := range []string{"/counters/name", "/counters/ratio"} {
+					_, err = storage.Increment(ctx, store, txn, storage.MustParsePath(path), 1)
+					if !storage.IsInvalidPatch(err) {
+						t.Fatalf("%s: expected invalid patch error but got %v", path, err)
+					}
+				}
+
+				_, err = storage.Increment(ctx, store, txn, storage.MustParsePath("/missing/counter"), 1)
+				if !storage.IsNotFound(err) {
+					t.Fatalf("expected not found error but got %v", err)
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			for path, exp := range map[string]string{"/counters/hits": "42", "/counters/misses": "-3", "/counters/name": `"x"`} {
+				if err := storage.Test(ctx, store, txn, storage.MustParsePath(path), util.MustUnmarshalJSON([]byte(exp))); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+)
+
+// CopyStore copies the document at srcPath in src to dstPath in dst. The
+// stores do not need to share a backend: the document is read from src as
+// plain Go values and written to dst with AddOp, creating any missing parent
+// objects and replacing any existing document at dstPath. If policies is true,
+// all policies in src are upserted into dst as well, keeping their ids.
+//
+// The read happens in a single read transaction on src and the writes in a
+// single write transaction on dst, so dst is left unchanged if copying fails.
+func CopyStore(ctx context.Context, src, dst Store, srcPath, dstPath Path, policies bool) error {
+	var value any
+	var modules map[string][]byte
+
+	err := Txn(ctx, src, TransactionParams{}, func(txn Transaction) error {
+		var err error
+		if value, err = ReadRaw(ctx, src, txn, srcPath); err != nil {
+			return err
+		}
+		if !policies {
+			return nil
+		}
+
+		ids, err := src.ListPolicies(ctx, txn)
+		if err != nil {
+			return err
+		}
+		modules = make(map[string][]byte, len(ids))
+		for _, id := range ids {
+			if modules[id], err = src.GetPolicy(ctx, txn, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return Txn(ctx, dst, WriteParams, func(txn Transaction) error {
+		if len(dstPath) > 0 {
+			if err := MakeDir(ctx, dst, txn, dstPath[:len(dstPath)-1]); err != nil {
+				return err
+			}
+		}
+		if err := dst.Write(ctx, txn, AddOp, dstPath, value); err != nil {
+			return err
+		}
+		for id, bs := range modules {
+			if err := dst.UpsertPolicy(ctx, txn, id, bs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
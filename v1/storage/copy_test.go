@@ -0,0 +1,77 @@
+package storage_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestCopyStore(t *testing.T) {
+	ctx := t.Context()
+	src := inmem.NewFromReaderWithOpts(strings.NewReader(`{"users": {"alice": {"roles": ["admin"]}}, "other": 1}`),
+		inmem.OptReturnASTValuesOnRead(true))
+
+	if err := storage.Txn(ctx, src, storage.WriteParams, func(txn storage.Transaction) error {
+		return src.UpsertPolicy(ctx, txn, "rbac.rego", []byte("package rbac"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		note     string
+		srcPath  string
+		dstPath  string
+		policies bool
+	}{
+		{note: "root", srcPath: "/", dstPath: "/"},
+		{note: "subtree", srcPath: "/users/alice", dstPath: "/copy/of/alice", policies: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			dst := inmem.New()
+			srcPath, dstPath := storage.MustParsePath(tc.srcPath), storage.MustParsePath(tc.dstPath)
+
+			if err := storage.CopyStore(ctx, src, dst, srcPath, dstPath, tc.policies); err != nil {
+				t.Fatal(err)
+			}
+
+			exp, err := storage.ReadOne(ctx, src, srcPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, err := storage.ReadOne(ctx, dst, dstPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ast.MustInterfaceToValue(exp).Compare(ast.MustInterfaceToValue(act)) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, dst)
+			defer dst.Abort(ctx, txn)
+
+			ids, err := dst.ListPolicies(ctx, txn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expIDs []string
+			if tc.policies {
+				expIDs = []string{"rbac.rego"}
+			}
+			if !slices.Equal(expIDs, ids) {
+				t.Fatalf("expected policies %v but got %v", expIDs, ids)
+			}
+		})
+	}
+
+	// Missing source documents are reported as such.
+	dst := inmem.New()
+	if err := storage.CopyStore(ctx, src, dst, storage.MustParsePath("/missing"), storage.MustParsePath("/x"), true); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+}
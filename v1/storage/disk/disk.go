@@ -125,9 +125,20 @@ const (
 )
 
 type metadata struct {
-	SchemaVersion    *int64         `json:"schema_version"`    // OPA-controlled data schema version
-	PartitionVersion *int64         `json:"partition_version"` // caller-supplied data layout version
-	Partitions       []storage.Path `json:"partitions"`        // caller-supplied data layout
+	SchemaVersion    *int64     `json:"schema_version"`    // OPA-controlled data schema version
+	PartitionVersion *int64     `json:"partition_version"` // caller-supplied data layout version
+	Partitions       [][]string `json:"partitions"`        // caller-supplied data layout
+}
+
+// segments returns paths as plain string slices, so that partitions are stored
+// as arrays of segments rather than using the JSON representation of
+// storage.Path, keeping the metadata readable by older versions.
+func segments(paths []storage.Path) [][]string {
+	ss := make([][]string, len(paths))
+	for i := range paths {
+		ss[i] = paths[i]
+	}
+	return ss
 }
 
 // systemPartition is the partition we add automatically: no user-defined partition
@@ -729,7 +740,7 @@ func (db *Store) init(ctx context.Context, txn *badger.Txn, partitions []storage
 		return db.setMetadata(txn, metadata{
 			SchemaVersion:    &schemaVersion,
 			PartitionVersion: &partitionVersion,
-			Partitions:       partitions,
+			Partitions:       segments(partitions),
 		})
 	}
 
@@ -742,13 +753,16 @@ func (db *Store) init(ctx context.Context, txn *badger.Txn, partitions []storage
 	return db.setMetadata(txn, metadata{
 		SchemaVersion:    &schemaVersion,
 		PartitionVersion: &partitionVersion,
-		Partitions:       partitions,
+		Partitions:       segments(partitions),
 	})
 }
 
 func (db *Store) validatePartitions(_ context.Context, txn *badger.Txn, existing metadata, partitions []storage.Path) error {
 
-	oldPathSet := pathSet(existing.Partitions)
+	oldPathSet := make(pathSet, len(existing.Partitions))
+	for i := range existing.Partitions {
+		oldPathSet[i] = existing.Partitions[i]
+	}
 	newPathSet := pathSet(partitions)
 	removedPartitions := oldPathSet.Diff(newPathSet)
 	addedPartitions := newPathSet.Diff(oldPathSet)
@@ -23,6 +23,7 @@ import (
 
 	"github.com/open-policy-agent/opa/v1/logging"
 	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/internal/storagetest"
 	"github.com/open-policy-agent/opa/v1/util"
 	"github.com/open-policy-agent/opa/v1/util/test"
 )
@@ -104,6 +105,24 @@ func (*testDump) do(t *testing.T, s *Store) {
 	}
 }
 
+type testFactory struct {
+	t *testing.T
+}
+
+func (f testFactory) New() storage.Store {
+	ctx := f.t.Context()
+	s, err := New(ctx, logging.NewNoOpLogger(), nil, Options{Dir: f.t.TempDir()})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	f.t.Cleanup(func() { s.Close(context.Background()) })
+	return s
+}
+
+func TestDeterminism(t *testing.T) {
+	storagetest.RunDeterminism(t, testFactory{t})
+}
+
 func TestPolicies(t *testing.T) {
 	t.Parallel()
 
@@ -39,6 +39,22 @@ const (
 	// PolicyNotSupportedErr indicate the caller attempted to perform a policy
 	// management operation against a store that does not support them.
 	PolicyNotSupportedErr = "storage_policy_not_supported_error"
+
+	// TestFailedErr indicates the document at a path did not match the
+	// expected value of a test operation.
+	TestFailedErr = "storage_test_failed_error"
+
+	// TypeMismatchErr indicates the document at a path is not of the type
+	// expected by the caller.
+	TypeMismatchErr = "storage_type_mismatch_error"
+
+	// ReadOnlyErr indicates the caller attempted to modify a store that has
+	// been made read-only.
+	ReadOnlyErr = "storage_read_only_error"
+
+	// FrozenErr indicates the caller attempted to modify a store that has
+	// been frozen.
+	FrozenErr = "storage_frozen_error"
 )
 
 // Error is the error type returned by the storage layer.
@@ -89,6 +105,42 @@ func IsInvalidTransaction(err error) bool {
 	return false
 }
 
+// IsTestFailed returns true if this error is a TestFailedErr.
+func IsTestFailed(err error) bool {
+	switch err := err.(type) {
+	case *Error:
+		return err.Code == TestFailedErr
+	}
+	return false
+}
+
+// IsTypeMismatch returns true if this error is a TypeMismatchErr.
+func IsTypeMismatch(err error) bool {
+	switch err := err.(type) {
+	case *Error:
+		return err.Code == TypeMismatchErr
+	}
+	return false
+}
+
+// IsReadOnly returns true if this error is a ReadOnlyErr.
+func IsReadOnly(err error) bool {
+	switch err := err.(type) {
+	case *Error:
+		return err.Code == ReadOnlyErr
+	}
+	return false
+}
+
+// IsFrozen returns true if this error is a FrozenErr.
+func IsFrozen(err error) bool {
+	switch err := err.(type) {
+	case *Error:
+		return err.Code == FrozenErr
+	}
+	return false
+}
+
 // IsIndexingNotSupported is a stub for backwards-compatibility.
 //
 // Deprecated: We no longer return IndexingNotSupported errors, so it is
@@ -102,6 +154,34 @@ func writeConflictError(path Path) *Error {
 	}
 }
 
+func notFoundError(path Path) *Error {
+	return &Error{
+		Code:    NotFoundErr,
+		Message: path.String() + ": document does not exist",
+	}
+}
+
+func testFailedError(path Path) *Error {
+	return &Error{
+		Code:    TestFailedErr,
+		Message: path.String() + ": document does not match expected value",
+	}
+}
+
+func typeMismatchError(path Path, expected string, actual any) *Error {
+	return &Error{
+		Code:    TypeMismatchErr,
+		Message: fmt.Sprintf("%v: expected %v but got %v", path, expected, typeName(actual)),
+	}
+}
+
+func readOnlyError(op string) *Error {
+	return &Error{
+		Code:    ReadOnlyErr,
+		Message: op + " rejected: store is read-only",
+	}
+}
+
 func triggersNotSupportedError() *Error {
 	return &Error{
 		Code: TriggersNotSupportedErr,
@@ -0,0 +1,59 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package inmem
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+)
+
+// Compressor compresses the policies held by the store. Decompress must
+// return the bytes passed to the Compress call that produced its input.
+type Compressor interface {
+	Compress(bs []byte) ([]byte, error)
+	Decompress(bs []byte) ([]byte, error)
+}
+
+// NoCompression is a Compressor that stores policies as they are.
+var NoCompression Compressor = noCompression{}
+
+type noCompression struct{}
+
+func (noCompression) Compress(bs []byte) ([]byte, error) {
+	return bs, nil
+}
+
+func (noCompression) Decompress(bs []byte) ([]byte, error) {
+	return bs, nil
+}
+
+func (db *store) compressPolicy(id string, bs []byte) ([]byte, error) {
+	if db.compressor == nil {
+		return bs, nil
+	}
+	stored, err := db.compressor.Compress(bs)
+	if err != nil {
+		return nil, &storage.Error{
+			Code:    storage.InternalErr,
+			Message: fmt.Sprintf("compress policy id %q: %v", id, err),
+		}
+	}
+	return stored, nil
+}
+
+func (db *store) decompressPolicy(id string, bs []byte) ([]byte, error) {
+	if db.compressor == nil {
+		return bs, nil
+	}
+	raw, err := db.compressor.Decompress(bs)
+	if err != nil {
+		return nil, &storage.Error{
+			Code:    storage.InternalErr,
+			Message: fmt.Sprintf("decompress policy id %q: %v", id, err),
+		}
+	}
+	return raw, nil
+}
@@ -17,12 +17,19 @@ package inmem
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/open-policy-agent/opa/internal/merge"
 	"github.com/open-policy-agent/opa/v1/ast"
@@ -59,6 +66,18 @@ func NewWithOpts(opts ...Opt) storage.Store {
 	return s
 }
 
+// Factory returns a storage.Factory creating empty in-memory stores with the
+// given options.
+func Factory(opts ...Opt) storage.Factory {
+	return factory(opts)
+}
+
+type factory []Opt
+
+func (f factory) New() storage.Store {
+	return NewWithOpts(f...)
+}
+
 // NewFromObject returns a new in-memory store from the supplied data object.
 func NewFromObject(data map[string]any) storage.Store {
 	return NewFromObjectWithOpts(data)
@@ -103,7 +122,7 @@ type store struct {
 	wmu      sync.Mutex                        // writer lock
 	xid      uint64                            // last generated transaction id
 	data     any                               // raw or AST data
-	policies map[string][]byte                 // raw policies
+	policies map[string][]byte                 // policies, as returned by compressor
 	triggers map[*handle]storage.TriggerConfig // registered triggers
 
 	// roundTripOnWrite, if true, means that every call to Write round trips the
@@ -114,13 +133,61 @@ type store struct {
 	// and return them on Read.
 	// FIXME: naming(?)
 	returnASTValuesOnRead bool
+
+	// maxTriggerEventSize, if positive, is the maximum number of changes
+	// passed to a trigger in a single event.
+	maxTriggerEventSize int
+
+	// frozen, if true, means that the store rejects write transactions and
+	// readers skip rmu. Set once by Freeze.
+	frozen atomic.Bool
+
+	// autoCreateParents, if true, means that add operations create missing
+	// parent objects instead of failing with a NotFoundErr.
+	autoCreateParents bool
+
+	// rawCacheSize, if positive, is the maximum number of documents in
+	// rawCache. rawCache holds documents converted by ReadRaw, keyed by path,
+	// and is cleared when data is committed.
+	rawCacheSize int
+	rawMu        sync.Mutex
+	rawCache     map[string]any
+
+	// maxNestingDepth, if positive, is the maximum depth of documents below
+	// the root accepted by Write.
+	maxNestingDepth int
+
+	// sortedPolicies, if true, means that ListPolicies returns ids in
+	// lexical order.
+	sortedPolicies bool
+
+	// compressor, if set, compresses policies when they are upserted and
+	// decompresses them when committed policies are read.
+	compressor Compressor
+
+	// validatePolicy, if set, is called on every policy upsert.
+	validatePolicy func(id string, bs []byte) error
+
+	// tracer, if set, is used to create spans for store operations.
+	tracer trace.Tracer
+
+	// slowTxnThreshold, if non-zero, is the duration after which writes and
+	// commits are reported through slowTxnLogf.
+	slowTxnThreshold time.Duration
+	slowTxnLogf      func(format string, args ...any)
 }
 
 type handle struct {
 	db *store
 }
 
-func (db *store) NewTransaction(_ context.Context, params ...storage.TransactionParams) (storage.Transaction, error) {
+func (db *store) NewTransaction(ctx context.Context, params ...storage.TransactionParams) (storage.Transaction, error) {
+	var span trace.Span
+	if db.tracer != nil {
+		_, span = db.tracer.Start(ctx, "inmem.NewTransaction")
+		defer span.End()
+	}
+
 	txn := &transaction{
 		xid: atomic.AddUint64(&db.xid, uint64(1)),
 		db:  db,
@@ -133,10 +200,27 @@ func (db *store) NewTransaction(_ context.Context, params ...storage.Transaction
 
 	if txn.write {
 		db.wmu.Lock()
+		// Check after acquiring the writer lock, as Freeze may have completed
+		// while waiting for it.
+		if db.frozen.Load() {
+			db.wmu.Unlock()
+			return nil, errors.FrozenErr
+		}
+	} else if db.frozen.Load() {
+		// Frozen stores are never modified again, so readers do not need to
+		// hold the lock.
+		txn.unlocked = true
 	} else {
 		db.rmu.RLock()
 	}
 
+	if span != nil {
+		span.SetAttributes(
+			attribute.Int64(attrTxnID, int64(txn.xid)),
+			attribute.Bool(attrTxnWrite, txn.write),
+		)
+	}
+
 	return txn, nil
 }
 
@@ -220,21 +304,43 @@ func (db *store) Truncate(ctx context.Context, txn storage.Transaction, params s
 	return nil
 }
 
-func (db *store) Commit(ctx context.Context, txn storage.Transaction) error {
+func (db *store) Commit(ctx context.Context, txn storage.Transaction) (err error) {
+	var span trace.Span
+	if db.tracer != nil {
+		ctx, span = db.tracer.Start(ctx, "inmem.Commit")
+		defer func() { endSpan(span, err) }()
+	}
+
 	underlying, err := db.underlying(txn)
 	if err != nil {
 		return err
 	}
+
+	if span != nil {
+		span.SetAttributes(
+			attribute.Int64(attrTxnID, int64(underlying.xid)),
+			attribute.Int(attrUpdateCount, underlying.updateCount()),
+			attribute.Int(attrPolicyCount, len(underlying.policies)),
+		)
+	}
 	if underlying.write {
+		if db.slowTxnThreshold > 0 {
+			defer db.logIfSlow(time.Now(), underlying.xid, "commit", underlying.updateCount())
+		}
 		db.rmu.Lock()
-		event := underlying.Commit()
-		db.runOnCommitTriggers(ctx, txn, event)
+		if db.oversizedEvent(underlying) {
+			underlying.commit(false)
+			db.runChunkedOnCommitTriggers(ctx, txn, underlying)
+		} else {
+			event, diff := underlying.Commit()
+			db.runOnCommitTriggers(ctx, txn, event, diff)
+		}
 		// Mark the transaction stale after executing triggers, so they can
 		// perform store operations if needed.
 		underlying.stale = true
 		db.rmu.Unlock()
 		db.wmu.Unlock()
-	} else {
+	} else if !underlying.unlocked {
 		db.rmu.RUnlock()
 	}
 	return nil
@@ -248,11 +354,20 @@ func (db *store) Abort(_ context.Context, txn storage.Transaction) {
 	underlying.stale = true
 	if underlying.write {
 		db.wmu.Unlock()
-	} else {
+	} else if !underlying.unlocked {
 		db.rmu.RUnlock()
 	}
 }
 
+// Freeze implements the storage.Freezer interface. It waits for any pending
+// write transaction to finish, after which write transactions are rejected
+// with a FrozenErr and read transactions no longer take the read lock.
+func (db *store) Freeze() {
+	db.wmu.Lock()
+	defer db.wmu.Unlock()
+	db.frozen.Store(true)
+}
+
 func (db *store) ListPolicies(_ context.Context, txn storage.Transaction) ([]string, error) {
 	underlying, err := db.underlying(txn)
 	if err != nil {
@@ -282,8 +397,8 @@ func (db *store) DeletePolicy(_ context.Context, txn storage.Transaction, id str
 	if err != nil {
 		return err
 	}
-	if _, err := underlying.GetPolicy(id); err != nil {
-		return err
+	if !underlying.hasPolicy(id) {
+		return errors.NewNotFoundErrorf("policy id %q", id)
 	}
 	return underlying.DeletePolicy(id)
 }
@@ -304,7 +419,13 @@ func (db *store) Register(_ context.Context, txn storage.Transaction, config sto
 	return h, nil
 }
 
-func (db *store) Read(_ context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+func (db *store) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (_ any, err error) {
+	if db.tracer != nil {
+		var span trace.Span
+		_, span = db.tracer.Start(ctx, "inmem.Read", trace.WithAttributes(attribute.String(attrPath, path.String())))
+		defer func() { endSpan(span, err) }()
+	}
+
 	underlying, err := db.underlying(txn)
 	if err != nil {
 		return nil, err
@@ -313,12 +434,91 @@ func (db *store) Read(_ context.Context, txn storage.Transaction, path storage.P
 	return underlying.Read(path)
 }
 
-func (db *store) Write(_ context.Context, txn storage.Transaction, op storage.PatchOp, path storage.Path, value any) error {
+// Kind implements the storage.Kinder interface. Unlike Read, it never copies
+// documents modified in txn.
+func (db *store) Kind(_ context.Context, txn storage.Transaction, path storage.Path) (storage.Kind, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return storage.KindInvalid, err
+	}
+	return underlying.Kind(path)
+}
+
+// ReadRaw implements the storage.RawReader interface. It returns documents as
+// native Go values, converting them when the store holds AST values. See
+// OptRawReadCacheSize for caching the converted documents.
+func (db *store) ReadRaw(_ context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := underlying.Read(path)
+	if err != nil || !db.returnASTValuesOnRead {
+		return v, err
+	}
+
+	// Transactions with pending updates may see data that differs from the
+	// committed data the cache is built from.
+	cacheable := db.rawCacheSize > 0 && underlying.updateCount() == 0
+	key := path.String()
+
+	if cacheable {
+		db.rawMu.Lock()
+		raw, ok := db.rawCache[key]
+		db.rawMu.Unlock()
+		if ok {
+			return raw, nil
+		}
+	}
+
+	av, ok := v.(ast.Value)
+	if !ok {
+		return v, nil
+	}
+	raw, err := ast.JSON(av)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		db.rawMu.Lock()
+		if db.rawCache == nil || len(db.rawCache) >= db.rawCacheSize {
+			db.rawCache = make(map[string]any, db.rawCacheSize)
+		}
+		db.rawCache[key] = raw
+		db.rawMu.Unlock()
+	}
+
+	return raw, nil
+}
+
+// clearRawCache drops all documents cached by ReadRaw.
+func (db *store) clearRawCache() {
+	db.rawMu.Lock()
+	defer db.rawMu.Unlock()
+	db.rawCache = nil
+}
+
+func (db *store) Write(ctx context.Context, txn storage.Transaction, op storage.PatchOp, path storage.Path, value any) (err error) {
+	if db.tracer != nil {
+		var span trace.Span
+		_, span = db.tracer.Start(ctx, "inmem.Write", trace.WithAttributes(
+			attribute.String(attrPath, path.String()),
+			attribute.Int(attrOp, int(op)),
+		))
+		defer func() { endSpan(span, err) }()
+	}
+
 	underlying, err := db.underlying(txn)
 	if err != nil {
 		return err
 	}
 
+	if db.slowTxnThreshold > 0 {
+		defer db.logIfSlow(time.Now(), underlying.xid, "write", 1)
+	}
+
 	if db.returnASTValuesOnRead || !util.NeedsRoundTrip(value) {
 		// Fast path when value is nil, bool, string or json.Number.
 		return underlying.Write(op, path, value)
@@ -334,6 +534,38 @@ func (db *store) Write(_ context.Context, txn storage.Transaction, op storage.Pa
 	return underlying.Write(op, path, *val)
 }
 
+// depth returns the number of levels of objects and arrays nested in v. Scalars
+// have depth 0.
+func depth(v any) int {
+	d := 0
+	switch v := v.(type) {
+	case map[string]any:
+		for _, x := range v {
+			d = max(d, depth(x))
+		}
+	case []any:
+		for _, x := range v {
+			d = max(d, depth(x))
+		}
+	case ast.Object:
+		v.Foreach(func(_, x *ast.Term) { d = max(d, depth(x.Value)) })
+	case *ast.Array:
+		v.Foreach(func(x *ast.Term) { d = max(d, depth(x.Value)) })
+	case ast.Set:
+		v.Foreach(func(x *ast.Term) { d = max(d, depth(x.Value)) })
+	case nil, bool, string, json.Number, float64, int, ast.Value:
+		return 0
+	default:
+		// Typed maps, slices and structs are measured by the document they
+		// convert to.
+		if x, err := ast.InterfaceToValue(v); err == nil {
+			return depth(x)
+		}
+		return 0
+	}
+	return d + 1
+}
+
 func (h *handle) Unregister(_ context.Context, txn storage.Transaction) {
 	underlying, err := h.db.underlying(txn)
 	if err != nil {
@@ -348,49 +580,181 @@ func (h *handle) Unregister(_ context.Context, txn storage.Transaction) {
 	delete(h.db.triggers, h)
 }
 
-func (db *store) runOnCommitTriggers(ctx context.Context, txn storage.Transaction, event storage.TriggerEvent) {
+func (db *store) runOnCommitTriggers(ctx context.Context, txn storage.Transaction, event storage.TriggerEvent, diff []storage.DataEvent) {
+	db.notifyTriggers(ctx, txn, event, diff, func(storage.TriggerConfig) (bool, bool) {
+		return true, false
+	})
+}
+
+// oversizedEvent returns true if the changes made by txn exceed the maximum
+// trigger event size and triggers are registered.
+func (db *store) oversizedEvent(txn *transaction) bool {
+	return db.maxTriggerEventSize > 0 && len(db.triggers) > 0 &&
+		txn.updateCount()+len(txn.policies) > db.maxTriggerEventSize
+}
+
+// runChunkedOnCommitTriggers notifies triggers of the changes made by a
+// committed transaction in chunks of at most maxTriggerEventSize changes.
+// Triggers that did not opt into chunked delivery only see the first chunk,
+// marked as truncated.
+func (db *store) runChunkedOnCommitTriggers(ctx context.Context, txn storage.Transaction, underlying *transaction) {
+	first := true
+	underlying.eventChunks(db.maxTriggerEventSize, func(event storage.TriggerEvent) bool {
+		more := false
+		db.notifyTriggers(ctx, txn, event, nil, func(t storage.TriggerConfig) (bool, bool) {
+			more = more || t.Chunked
+			return t.Chunked || first, !t.Chunked
+		})
+		first = false
+		return more
+	})
+}
+
+// notifyTriggers invokes the triggers for which include returns true, with
+// event marked as truncated if it also returns true. If diff is not nil,
+// triggers that asked for structural diffs get it as the data changes instead
+// of those in event.
+func (db *store) notifyTriggers(ctx context.Context, txn storage.Transaction, event storage.TriggerEvent, diff []storage.DataEvent, include func(storage.TriggerConfig) (deliver, truncated bool)) {
 	// While it's unlikely, the API allows one trigger to be configured to want
 	// data conversion, and another that doesn't. So let's handle that properly.
-	var wantsDataConversion bool
-	if db.returnASTValuesOnRead && len(event.Data) > 0 {
-		for _, t := range db.triggers {
-			if !t.SkipDataConversion {
-				wantsDataConversion = true
-				break
+	// Converted data is computed at most once for each of event and diff.
+	var converted, convertedDiff []storage.DataEvent
+
+	for _, t := range db.triggers {
+		deliver, truncated := include(t)
+		if !deliver {
+			continue
+		}
+		e := event
+		if t.Diff && diff != nil {
+			e.Data = diff
+		}
+		if db.returnASTValuesOnRead && !t.SkipDataConversion && len(e.Data) > 0 {
+			if t.Diff && diff != nil {
+				if convertedDiff == nil {
+					convertedDiff = convertDataEvents(diff)
+				}
+				e.Data = convertedDiff
+			} else {
+				if converted == nil {
+					converted = convertDataEvents(event.Data)
+				}
+				e.Data = converted
 			}
 		}
+		e.Truncated = truncated
+		t.OnCommit(ctx, txn, e)
 	}
+}
 
-	var converted storage.TriggerEvent
-	if wantsDataConversion {
-		converted = storage.TriggerEvent{
-			Policy:  event.Policy,
-			Data:    make([]storage.DataEvent, 0, len(event.Data)),
-			Context: event.Context,
-		}
-
-		for _, dataEvent := range event.Data {
-			if astData, ok := dataEvent.Data.(ast.Value); ok {
-				jsn, err := ast.ValueToInterface(astData, illegalResolver{})
-				if err != nil {
-					panic(err)
-				}
-				converted.Data = append(converted.Data, storage.DataEvent{
-					Path:    dataEvent.Path,
-					Data:    jsn,
-					Removed: dataEvent.Removed,
-				})
+// convertDataEvents returns events with their AST data converted to native Go
+// values. Events without AST data are omitted.
+func convertDataEvents(events []storage.DataEvent) []storage.DataEvent {
+	converted := make([]storage.DataEvent, 0, len(events))
+	for _, dataEvent := range events {
+		if astData, ok := dataEvent.Data.(ast.Value); ok {
+			jsn, err := ast.ValueToInterface(astData, illegalResolver{})
+			if err != nil {
+				panic(err)
 			}
+			converted = append(converted, storage.DataEvent{
+				Path:    dataEvent.Path,
+				Data:    jsn,
+				Removed: dataEvent.Removed,
+			})
 		}
 	}
+	return converted
+}
 
+// wantsDiff returns true if a registered trigger asked for structural diffs.
+func (db *store) wantsDiff() bool {
 	for _, t := range db.triggers {
-		if wantsDataConversion && !t.SkipDataConversion {
-			t.OnCommit(ctx, txn, converted)
-		} else {
-			t.OnCommit(ctx, txn, event)
+		if t.Diff {
+			return true
 		}
 	}
+	return false
+}
+
+// diffBase returns the document that action replaces, as a native Go value,
+// or nil if the change cannot be described as a diff: when action removes a
+// document, adds a new one, inserts into an array, or replaces null.
+func (db *store) diffBase(action dataUpdate) any {
+	path := action.Path()
+	if action.Remove() {
+		return nil
+	}
+	if len(path) > 0 {
+		parent, err := pointer(db.data, path[:len(path)-1])
+		if err != nil {
+			return nil
+		}
+		switch parent.(type) {
+		case map[string]any, ast.Object:
+		default:
+			return nil
+		}
+	}
+	old, err := pointer(db.data, path)
+	if err != nil {
+		return nil
+	}
+	if v, ok := old.(ast.Value); ok {
+		if old, err = ast.JSON(v); err != nil {
+			return nil
+		}
+	}
+	return old
+}
+
+// appendDiff appends to events the structural changes between old and the
+// document written by event. If old is nil, event is appended as is.
+func appendDiff(events []storage.DataEvent, event storage.DataEvent, old any) []storage.DataEvent {
+	if old == nil {
+		return append(events, event)
+	}
+	current := event.Data
+	if v, ok := current.(ast.Value); ok {
+		var err error
+		if current, err = ast.JSON(v); err != nil {
+			return append(events, event)
+		}
+	}
+	for _, p := range storage.DiffToPatch(old, current) {
+		// Take the changed value from the written document, so that it keeps
+		// the store's representation.
+		data, _ := pointer(event.Data, p.Path)
+		events = append(events, storage.DataEvent{
+			Path:    storage.Path(slices.Concat(event.Path, p.Path)),
+			Data:    data,
+			Removed: p.Op == storage.RemoveOp,
+		})
+	}
+	return events
+}
+
+const (
+	attrTxnID       = "opa.storage.txn_id"
+	attrTxnWrite    = "opa.storage.txn_write"
+	attrPath        = "opa.storage.path"
+	attrOp          = "opa.storage.op"
+	attrUpdateCount = "opa.storage.update_count"
+	attrPolicyCount = "opa.storage.policy_count"
+)
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (db *store) logIfSlow(start time.Time, xid uint64, op string, paths int) {
+	if d := time.Since(start); d >= db.slowTxnThreshold {
+		db.slowTxnLogf("slow storage transaction: txn=%d op=%s paths=%d duration=%v", xid, op, paths, d)
+	}
 }
 
 type illegalResolver struct{}
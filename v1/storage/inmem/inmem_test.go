@@ -9,14 +9,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"reflect"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/open-policy-agent/opa/internal/file/archive"
 	"github.com/open-policy-agent/opa/v1/ast"
 	"github.com/open-policy-agent/opa/v1/bundle"
 	storageerrors "github.com/open-policy-agent/opa/v1/storage/internal/errors"
+	"github.com/open-policy-agent/opa/v1/storage/internal/storagetest"
 
 	"github.com/open-policy-agent/opa/v1/storage"
 	"github.com/open-policy-agent/opa/v1/util"
@@ -267,6 +278,27 @@ func TestInMemoryWrite(t *testing.T) {
 	}
 }
 
+func TestInMemoryReadPreservesNumberLiterals(t *testing.T) {
+	data := `{"a":1,"b":1.50,"c":1e3}`
+
+	ctx := t.Context()
+	store := NewFromReader(strings.NewReader(data))
+
+	v, err := storage.ReadOne(ctx, store, storage.RootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(bs) != data {
+		t.Fatalf("expected %s but got %s", data, bs)
+	}
+}
+
 func TestInMemoryWriteOfStruct(t *testing.T) {
 	type B struct {
 		Bar int `json:"bar"`
@@ -1397,3 +1429,898 @@ func TestOptRoundTripOnWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestOptTracerInvalidTransaction(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	ctx := t.Context()
+	store := NewWithOpts(OptTracer(provider.Tracer("test")))
+	other := New()
+
+	for _, txn := range []storage.Transaction{nil, storage.NewTransactionOrDie(ctx, other)} {
+		if err := store.Commit(ctx, txn); !storage.IsInvalidTransaction(err) {
+			t.Fatalf("expected invalid transaction error but got %v", err)
+		}
+	}
+
+	for _, span := range recorder.Ended() {
+		if span.Status().Code != codes.Error {
+			t.Fatalf("expected span %q to record the error", span.Name())
+		}
+	}
+}
+
+func TestOptTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	ctx := t.Context()
+	store := NewWithOpts(OptTracer(provider.Tracer("test")))
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/a"), map[string]any{"b": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Read(ctx, txn, storage.MustParsePath("/a/c")); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+	if err := store.UpsertPolicy(ctx, txn, "x.rego", []byte("package x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+
+	exp := []struct {
+		name   string
+		attrs  map[attribute.Key]attribute.Value
+		status codes.Code
+	}{
+		{
+			name: "inmem.NewTransaction",
+			attrs: map[attribute.Key]attribute.Value{
+				attrTxnID:    attribute.Int64Value(int64(txn.ID())),
+				attrTxnWrite: attribute.BoolValue(true),
+			},
+		},
+		{
+			name: "inmem.Write",
+			attrs: map[attribute.Key]attribute.Value{
+				attrPath: attribute.StringValue("/a"),
+				attrOp:   attribute.IntValue(int(storage.AddOp)),
+			},
+		},
+		{
+			name: "inmem.Read",
+			attrs: map[attribute.Key]attribute.Value{
+				attrPath: attribute.StringValue("/a/c"),
+			},
+			status: codes.Error,
+		},
+		{
+			name: "inmem.Commit",
+			attrs: map[attribute.Key]attribute.Value{
+				attrTxnID:       attribute.Int64Value(int64(txn.ID())),
+				attrUpdateCount: attribute.IntValue(1),
+				attrPolicyCount: attribute.IntValue(1),
+			},
+		},
+	}
+
+	if len(spans) != len(exp) {
+		t.Fatalf("expected %d spans but got %d", len(exp), len(spans))
+	}
+
+	for i, e := range exp {
+		span := spans[i]
+		if span.Name() != e.name {
+			t.Fatalf("expected span %d to be %q but got %q", i, e.name, span.Name())
+		}
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		for k, v := range e.attrs {
+			if attrs[k] != v {
+				t.Errorf("%s: expected attribute %s=%v but got %v", e.name, k, v.Emit(), attrs[k].Emit())
+			}
+		}
+		if span.Status().Code != e.status {
+			t.Errorf("%s: expected status %v but got %v", e.name, e.status, span.Status().Code)
+		}
+	}
+}
+
+func TestOptSlowTransactionThreshold(t *testing.T) {
+	var logs []string
+	logf := func(format string, args ...any) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+
+	ctx := t.Context()
+	store := NewWithOpts(OptSlowTransactionThreshold(10*time.Millisecond, logf))
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if _, err := store.Register(ctx, txn, storage.TriggerConfig{
+		OnCommit: func(context.Context, storage.Transaction, storage.TriggerEvent) {
+			time.Sleep(20 * time.Millisecond)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	// The registering commit already runs the slow trigger, only consider the
+	// commit below.
+	logs = nil
+
+	txn = storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	for _, path := range []string{"/a", "/b"} {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath(path), "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	// The writes may be reported too on a loaded machine, only the commit is
+	// known to exceed the threshold.
+	var commits []string
+	for _, l := range logs {
+		if strings.Contains(l, " op=commit ") {
+			commits = append(commits, l)
+		}
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected exactly one slow commit log but got %v", logs)
+	}
+
+	prefix := fmt.Sprintf("slow storage transaction: txn=%d op=commit paths=2 duration=", txn.ID())
+	if !strings.HasPrefix(commits[0], prefix) {
+		t.Fatalf("expected log to start with %q but got %q", prefix, commits[0])
+	}
+}
+
+func TestOptAutoCreateParents(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			data := `{"a": {"xs": [{}]}, "s": "x"}`
+
+			// By default, the parent of the written path must exist.
+			store := NewFromReaderWithOpts(strings.NewReader(data), OptReturnASTValuesOnRead(astValues))
+			err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/a/b/c"), "v")
+			if !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+
+			store = NewFromReaderWithOpts(strings.NewReader(data),
+				OptReturnASTValuesOnRead(astValues),
+				OptAutoCreateParents(true))
+
+			txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+			writes := []string{"/a/b/c", "/a/b/d/e", "/a/xs/0/y/z", "/n/m"}
+			for _, path := range writes {
+				if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath(path), "v"); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+			}
+
+			// Only objects are created. Replace and remove still require the
+			// path to exist.
+			failures := []struct {
+				op   storage.PatchOp
+				path string
+			}{
+				{storage.AddOp, "/a/xs/1/y"},
+				{storage.AddOp, "/s/t/u"},
+				{storage.ReplaceOp, "/p/q"},
+				{storage.RemoveOp, "/p/q"},
+			}
+			for _, tc := range failures {
+				if err := store.Write(ctx, txn, tc.op, storage.MustParsePath(tc.path), "v"); err == nil {
+					t.Fatalf("%s: expected error", tc.path)
+				}
+			}
+
+			if err := store.Commit(ctx, txn); err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := storage.ReadOne(ctx, store, storage.RootPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exp := `{"a": {"b": {"c": "v", "d": {"e": "v"}}, "xs": [{"y": {"z": "v"}}]}, "n": {"m": "v"}, "s": "x"}`
+			if ast.MustInterfaceToValue(act).Compare(ast.MustParseTerm(exp).Value) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestOptPolicyValidation(t *testing.T) {
+	validate := func(id string, bs []byte) error {
+		if !bytes.HasPrefix(bs, []byte("package ")) {
+			return fmt.Errorf("%s: not a rego module", id)
+		}
+		return nil
+	}
+
+	ctx := t.Context()
+	store := NewWithOpts(OptPolicyValidation(validate))
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	if err := store.UpsertPolicy(ctx, txn, "ok.rego", []byte("package ok")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.UpsertPolicy(ctx, txn, "bad.rego", []byte("garbage"))
+	if err == nil || err.Error() != "bad.rego: not a rego module" {
+		t.Fatalf("expected validation error but got %v", err)
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "ok.rego" {
+		t.Fatalf("expected only ok.rego to be stored but got %v", ids)
+	}
+}
+
+func TestOptPolicyValidationTruncate(t *testing.T) {
+	validate := func(id string, bs []byte) error {
+		if bytes.Contains(bs, []byte("forbidden")) {
+			return fmt.Errorf("%s: forbidden package", id)
+		}
+		return nil
+	}
+
+	ctx := t.Context()
+	store := NewWithOpts(OptPolicyValidation(validate))
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	defer store.Abort(ctx, txn)
+
+	params := storage.WriteParams
+	params.BasePaths = []string{""}
+
+	err := store.Truncate(ctx, txn, params, bundleIterator(t, map[string]string{
+		"/ok.rego":  "package ok",
+		"/bad.rego": "package forbidden",
+	}))
+	if err == nil || err.Error() != "bad.rego: forbidden package" {
+		t.Fatalf("expected validation error but got %v", err)
+	}
+}
+
+// prefixCompressor marks compressed policies so tests can tell them apart
+// from uncompressed ones.
+type prefixCompressor struct{}
+
+func (prefixCompressor) Compress(bs []byte) ([]byte, error) {
+	return append([]byte("z:"), bs...), nil
+}
+
+func (prefixCompressor) Decompress(bs []byte) ([]byte, error) {
+	raw, ok := bytes.CutPrefix(bs, []byte("z:"))
+	if !ok {
+		return nil, fmt.Errorf("not compressed: %q", bs)
+	}
+	return raw, nil
+}
+
+func TestOptPolicyCompressor(t *testing.T) {
+	for _, tc := range []struct {
+		note       string
+		compressor Compressor
+		stored     string
+	}{
+		{note: "default", stored: "package a"},
+		{note: "none", compressor: NoCompression, stored: "package a"},
+		{note: "custom", compressor: prefixCompressor{}, stored: "z:package a"},
+	} {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := t.Context()
+			var opts []Opt
+			if tc.compressor != nil {
+				opts = append(opts, OptPolicyCompressor(tc.compressor))
+			}
+			db := NewWithOpts(opts...).(*store)
+
+			var events []storage.PolicyEvent
+			txn := storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+			if _, err := db.Register(ctx, txn, storage.TriggerConfig{
+				OnCommit: func(_ context.Context, _ storage.Transaction, event storage.TriggerEvent) {
+					events = append(events, event.Policy...)
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Commit(ctx, txn); err != nil {
+				t.Fatal(err)
+			}
+
+			txn = storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+			if err := db.UpsertPolicy(ctx, txn, "a.rego", []byte("package a")); err != nil {
+				t.Fatal(err)
+			}
+			bs, err := db.GetPolicy(ctx, txn, "a.rego")
+			if err != nil || string(bs) != "package a" {
+				t.Fatalf("expected uncommitted policy but got %q, %v", bs, err)
+			}
+			if err := db.Commit(ctx, txn); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events) != 1 || string(events[0].Data) != "package a" {
+				t.Fatalf("expected trigger to receive uncompressed policy but got %v", events)
+			}
+			if stored := string(db.policies["a.rego"]); stored != tc.stored {
+				t.Fatalf("expected stored policy %q but got %q", tc.stored, stored)
+			}
+
+			txn = storage.NewTransactionOrDie(ctx, db)
+			defer db.Abort(ctx, txn)
+
+			bs, err = db.GetPolicy(ctx, txn, "a.rego")
+			if err != nil || string(bs) != "package a" {
+				t.Fatalf("expected committed policy but got %q, %v", bs, err)
+			}
+		})
+	}
+}
+
+func TestOptPolicyCompressorError(t *testing.T) {
+	ctx := t.Context()
+	db := NewWithOpts(OptPolicyCompressor(prefixCompressor{})).(*store)
+	db.policies["a.rego"] = []byte("package a")
+
+	txn := storage.NewTransactionOrDie(ctx, db)
+	defer db.Abort(ctx, txn)
+
+	_, err := db.GetPolicy(ctx, txn, "a.rego")
+	if serr, ok := err.(*storage.Error); !ok || serr.Code != storage.InternalErr {
+		t.Fatalf("expected internal error but got %v", err)
+	}
+}
+
+func TestOptPolicyCompressorDelete(t *testing.T) {
+	ctx := t.Context()
+	db := NewWithOpts(OptPolicyCompressor(prefixCompressor{})).(*store)
+	// Deleting must not decompress the policy, which would fail here.
+	db.policies["a.rego"] = []byte("package a")
+
+	txn := storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+	if err := db.DeletePolicy(ctx, txn, "a.rego"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeletePolicy(ctx, txn, "a.rego"); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+	if err := db.DeletePolicy(ctx, txn, "b.rego"); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+	if err := db.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.policies["a.rego"]; ok {
+		t.Fatal("expected policy to be deleted")
+	}
+}
+
+func TestInMemoryFreeze(t *testing.T) {
+	ctx := t.Context()
+	db := NewFromReader(strings.NewReader(`{"a": {"b": 1}}`)).(*store)
+
+	// Read transactions opened before freezing hold the read lock.
+	before := storage.NewTransactionOrDie(ctx, db)
+
+	var freezer storage.Freezer = db
+	freezer.Freeze()
+
+	if _, err := db.NewTransaction(ctx, storage.WriteParams); !storage.IsFrozen(err) {
+		t.Fatalf("expected frozen error but got %v", err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, db)
+
+	v, err := db.Read(ctx, txn, storage.MustParsePath("/a/b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != json.Number("1") {
+		t.Fatalf("expected 1 but got %v", v)
+	}
+
+	db.Abort(ctx, before)
+
+	// No reader holds the lock anymore, even though txn is still open.
+	if !db.rmu.TryLock() {
+		t.Fatal("expected read transaction on frozen store not to hold the read lock")
+	}
+	db.rmu.Unlock()
+
+	if err := db.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOptMaxTriggerEventSize(t *testing.T) {
+	const limit, writes, policies = 10, 95, 3
+
+	ctx := t.Context()
+	store := NewWithOpts(OptMaxTriggerEventSize(limit))
+
+	var chunks, truncated []storage.TriggerEvent
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	for _, config := range []storage.TriggerConfig{
+		{
+			Chunked: true,
+			OnCommit: func(_ context.Context, _ storage.Transaction, event storage.TriggerEvent) {
+				chunks = append(chunks, event)
+			},
+		},
+		{
+			OnCommit: func(_ context.Context, _ storage.Transaction, event storage.TriggerEvent) {
+				truncated = append(truncated, event)
+			},
+		},
+	} {
+		if _, err := store.Register(ctx, txn, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, truncated = nil, nil
+
+	txn = storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	for i := range writes {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.Path{strconv.Itoa(i)}, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range policies {
+		if err := store.UpsertPolicy(ctx, txn, fmt.Sprintf("p%d.rego", i), []byte("package p")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	var data, policy int
+	for _, event := range chunks {
+		if n := len(event.Data) + len(event.Policy); n > limit {
+			t.Fatalf("expected at most %d changes per chunk but got %d", limit, n)
+		}
+		if event.Truncated {
+			t.Fatal("expected chunks not to be truncated")
+		}
+		data += len(event.Data)
+		policy += len(event.Policy)
+	}
+	if len(chunks) != 10 || data != writes || policy != policies {
+		t.Fatalf("expected all changes in 10 chunks but got %d chunks, %d data and %d policy events", len(chunks), data, policy)
+	}
+
+	if len(truncated) != 1 || !truncated[0].Truncated || len(truncated[0].Data) != limit {
+		t.Fatalf("expected one truncated event with %d changes but got %v", limit, truncated)
+	}
+
+	// Small transactions are not affected.
+	chunks, truncated = nil, nil
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/x"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 1 || len(truncated) != 1 || truncated[0].Truncated {
+		t.Fatalf("expected single complete events but got %v and %v", chunks, truncated)
+	}
+}
+
+func TestFactory(t *testing.T) {
+	ctx := t.Context()
+	f := Factory(OptReturnASTValuesOnRead(true))
+
+	a, b := f.New(), f.New()
+
+	if err := storage.WriteOne(ctx, a, storage.AddOp, storage.MustParsePath("/x"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := storage.ReadOne(ctx, a, storage.MustParsePath("/x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(ast.Number); !ok {
+		t.Fatalf("expected options to be applied, got %T", v)
+	}
+
+	if _, err := storage.ReadOne(ctx, b, storage.MustParsePath("/x")); !storage.IsNotFound(err) {
+		t.Fatalf("expected stores to be independent, got %v", err)
+	}
+}
+
+func TestDeterminism(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			storagetest.RunDeterminism(t, Factory(OptReturnASTValuesOnRead(astValues), OptSortedPolicies(true)))
+		})
+	}
+}
+
+func TestOptMaxNestingDepth(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			db := NewFromObjectWithOpts(map[string]any{"a": map[string]any{}},
+				OptMaxNestingDepth(3), OptReturnASTValuesOnRead(astValues))
+
+			tests := []struct {
+				path  string
+				value string
+				ok    bool
+			}{
+				{path: "/a/b", value: `{"c": 1}`, ok: true},
+				{path: "/a/b", value: `{"c": [1]}`},
+				{path: "/a", value: `{"b": {"c": {"d": 1}}}`},
+				{path: "/x", value: `[[1, 2], {"y": 3}]`, ok: true},
+			}
+
+			for _, tc := range tests {
+				path := storage.MustParsePath(tc.path)
+				err := storage.WriteOne(ctx, db, storage.AddOp, path, util.MustUnmarshalJSON([]byte(tc.value)))
+				if tc.ok {
+					if err != nil {
+						t.Fatalf("%v %v: unexpected error: %v", tc.path, tc.value, err)
+					}
+					continue
+				}
+				if !storage.IsInvalidPatch(err) {
+					t.Fatalf("%v %v: expected invalid patch error but got %v", tc.path, tc.value, err)
+				}
+			}
+
+			act, err := storage.ReadOne(ctx, db, storage.RootPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := util.MustUnmarshalJSON([]byte(`{"a": {"b": {"c": 1}}, "x": [[1, 2], {"y": 3}]}`))
+			if ast.MustInterfaceToValue(act).Compare(ast.MustInterfaceToValue(exp)) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+type nestedDoc struct {
+	A struct {
+		B []int `json:"b"`
+	} `json:"a"`
+}
+
+func TestOptMaxNestingDepthTypedValues(t *testing.T) {
+	values := map[string]any{
+		"typed map": map[string]any{"a": map[string]map[string]string{"b": {"c": "d"}}},
+		"struct":    nestedDoc{},
+		"pointer":   &nestedDoc{},
+	}
+
+	modes := map[string]Opt{
+		"round trip": OptRoundTripOnWrite(true),
+		"raw":        OptRoundTripOnWrite(false),
+		"ast":        OptReturnASTValuesOnRead(true),
+	}
+
+	for mode, opt := range modes {
+		for note, value := range values {
+			t.Run(mode+"/"+note, func(t *testing.T) {
+				db := NewWithOpts(opt, OptMaxNestingDepth(2))
+				err := storage.WriteOne(t.Context(), db, storage.AddOp, storage.MustParsePath("/x"), value)
+				if !storage.IsInvalidPatch(err) {
+					t.Fatalf("expected invalid patch error but got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestOptMaxNestingDepthTruncate(t *testing.T) {
+	ctx := t.Context()
+	db := NewWithOpts(OptMaxNestingDepth(2))
+	txn := storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+	defer db.Abort(ctx, txn)
+
+	params := storage.WriteParams
+	params.BasePaths = []string{""}
+
+	err := db.Truncate(ctx, txn, params, bundleIterator(t, map[string]string{
+		"/a/data.json": `{"b": {"c": 1}}`,
+	}))
+	if !storage.IsInvalidPatch(err) {
+		t.Fatalf("expected invalid patch error but got %v", err)
+	}
+}
+
+// bundleIterator returns an iterator over a bundle containing files.
+func bundleIterator(t *testing.T, files map[string]string) storage.Iterator {
+	t.Helper()
+
+	archiveFiles := make([][2]string, 0, len(files))
+	for name, content := range files {
+		archiveFiles = append(archiveFiles, [2]string{name, content})
+	}
+
+	b, err := bundle.NewReader(archive.MustWriteTarGz(archiveFiles)).WithLazyLoadingMode(true).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundle.NewIterator(b.Raw)
+}
+
+func TestReadRaw(t *testing.T) {
+	ctx := t.Context()
+	db := NewFromObjectWithOpts(map[string]any{"users": map[string]any{"alice": map[string]any{"roles": []any{"admin"}}}},
+		OptReturnASTValuesOnRead(true), OptRawReadCacheSize(8))
+	path := storage.MustParsePath("/users/alice")
+
+	readRaw := func() any {
+		t.Helper()
+		txn := storage.NewTransactionOrDie(ctx, db)
+		defer db.Abort(ctx, txn)
+		v, err := storage.ReadRaw(ctx, db, txn, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	v, err := storage.ReadOne(ctx, db, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(ast.Object); !ok {
+		t.Fatalf("expected ast.Object from Read but got %T", v)
+	}
+
+	raw := readRaw()
+	exp := map[string]any{"roles": []any{"admin"}}
+	if !reflect.DeepEqual(exp, raw) {
+		t.Fatalf("expected %v from ReadRaw but got %v", exp, raw)
+	}
+	if again := readRaw(); reflect.ValueOf(again).Pointer() != reflect.ValueOf(raw).Pointer() {
+		t.Fatal("expected cached document to be reused")
+	}
+
+	if err := storage.WriteOne(ctx, db, storage.AddOp, storage.MustParsePath("/users/alice/roles/-"), "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp = map[string]any{"roles": []any{"admin", "dev"}}
+	if raw := readRaw(); !reflect.DeepEqual(exp, raw) {
+		t.Fatalf("expected %v after commit but got %v", exp, raw)
+	}
+
+	// Pending updates are visible to ReadRaw in the writing transaction.
+	txn := storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+	defer db.Abort(ctx, txn)
+	if err := db.Write(ctx, txn, storage.RemoveOp, storage.MustParsePath("/users/alice/roles"), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw, err = storage.ReadRaw(ctx, db, txn, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := map[string]any{}; !reflect.DeepEqual(exp, raw) {
+		t.Fatalf("expected %v in write transaction but got %v", exp, raw)
+	}
+}
+
+func TestInMemoryTriggersDiff(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			db := NewFromReaderWithOpts(strings.NewReader(`{"users": {"alice": {"name": "Alice", "profile": {"age": 30, "city": "Oslo"}}}}`),
+				OptReturnASTValuesOnRead(astValues))
+
+			var diff, coarse []storage.DataEvent
+			err := storage.Txn(ctx, db, storage.WriteParams, func(txn storage.Transaction) error {
+				if _, err := db.Register(ctx, txn, storage.TriggerConfig{
+					Diff: true,
+					OnCommit: func(_ context.Context, _ storage.Transaction, event storage.TriggerEvent) {
+						diff = event.Data
+					},
+				}); err != nil {
+					return err
+				}
+				_, err := db.Register(ctx, txn, storage.TriggerConfig{
+					OnCommit: func(_ context.Context, _ storage.Transaction, event storage.TriggerEvent) {
+						coarse = event.Data
+					},
+				})
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value := util.MustUnmarshalJSON([]byte(`{"name": "Alice", "profile": {"age": 31, "city": "Oslo"}}`))
+			if err := storage.WriteOne(ctx, db, storage.AddOp, storage.MustParsePath("/users/alice"), value); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(diff) != 1 {
+				t.Fatalf("expected exactly one diff event but got %v", diff)
+			}
+			if exp := storage.MustParsePath("/users/alice/profile/age"); !diff[0].Path.Equal(exp) {
+				t.Fatalf("expected diff for %v but got %v", exp, diff[0].Path)
+			}
+			if ast.MustInterfaceToValue(diff[0].Data).Compare(ast.Number("31")) != 0 || diff[0].Removed {
+				t.Fatalf("expected diff to set 31 but got %v", diff[0])
+			}
+
+			if len(coarse) != 1 || !coarse[0].Path.Equal(storage.MustParsePath("/users/alice")) {
+				t.Fatalf("expected coarse event for /users/alice but got %v", coarse)
+			}
+		})
+	}
+}
+
+func TestInMemoryConcurrentReadsSeeWholeCommits(t *testing.T) {
+	const readers, commits = 8, 200
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			db := NewFromObjectWithOpts(map[string]any{"a": json.Number("0"), "b": map[string]any{"c": json.Number("0")}},
+				OptReturnASTValuesOnRead(astValues))
+			pathA, pathC := storage.MustParsePath("/a"), storage.MustParsePath("/b/c")
+
+			done := make(chan struct{})
+			errs := make(chan error, readers)
+			var wg sync.WaitGroup
+
+			for range readers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-done:
+							return
+						default:
+						}
+						txn := storage.NewTransactionOrDie(ctx, db)
+						a, errA := db.Read(ctx, txn, pathA)
+						c, errC := db.Read(ctx, txn, pathC)
+						db.Abort(ctx, txn)
+						if errA != nil || errC != nil {
+							errs <- fmt.Errorf("read failed: %v, %v", errA, errC)
+							return
+						}
+						if ast.MustInterfaceToValue(a).Compare(ast.MustInterfaceToValue(c)) != 0 {
+							errs <- fmt.Errorf("observed partial commit: a=%v c=%v", a, c)
+							return
+						}
+					}
+				}()
+			}
+
+			for i := 1; i <= commits; i++ {
+				n := json.Number(strconv.Itoa(i))
+				err := storage.Txn(ctx, db, storage.WriteParams, func(txn storage.Transaction) error {
+					if err := db.Write(ctx, txn, storage.ReplaceOp, pathA, n); err != nil {
+						return err
+					}
+					return db.Write(ctx, txn, storage.ReplaceOp, pathC, n)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			close(done)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInMemoryTxnUpdatesDoNotOverlap(t *testing.T) {
+	keys := []string{"a", "b", "c"}
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	randomPath := func() storage.Path {
+		path := make(storage.Path, 1+rng.IntN(3))
+		for i := range path {
+			path[i] = keys[rng.IntN(len(keys))]
+		}
+		return path
+	}
+
+	randomValue := func() any {
+		if rng.IntN(2) == 0 {
+			return map[string]any{}
+		}
+		return json.Number(strconv.Itoa(rng.IntN(100)))
+	}
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+
+			for range 50 {
+				db := NewWithOpts(OptReturnASTValuesOnRead(astValues))
+				txn := storage.NewTransactionOrDie(ctx, db, storage.WriteParams)
+
+				for range 100 {
+					op, value := storage.AddOp, randomValue()
+					if rng.IntN(3) == 0 {
+						op, value = storage.RemoveOp, nil
+					}
+					// Many of the random writes are invalid, only the invariant matters.
+					_ = db.Write(ctx, txn, op, randomPath(), value)
+					assertNoOverlappingUpdates(t, txn.(*transaction))
+				}
+
+				// Reading through the pending updates must agree with the result
+				// of applying them on commit.
+				exp, err := db.Read(ctx, txn, storage.RootPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				exp = deepcpy(exp)
+
+				if err := db.Commit(ctx, txn); err != nil {
+					t.Fatal(err)
+				}
+
+				act, err := storage.ReadOne(ctx, db, storage.RootPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ast.MustInterfaceToValue(exp).Compare(ast.MustInterfaceToValue(act)) != 0 {
+					t.Fatalf("expected committed data %v but got %v", exp, act)
+				}
+			}
+		})
+	}
+}
+
+func assertNoOverlappingUpdates(t *testing.T, txn *transaction) {
+	t.Helper()
+	if txn.updates == nil {
+		return
+	}
+	for a := txn.updates.Front(); a != nil; a = a.Next() {
+		for b := a.Next(); b != nil; b = b.Next() {
+			pa, pb := a.Value.(dataUpdate).Path(), b.Value.(dataUpdate).Path()
+			if pa.HasPrefix(pb) || pb.HasPrefix(pa) {
+				t.Fatalf("overlapping updates in transaction: %v and %v", pa, pb)
+			}
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package inmem
 
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
 // An Opt modifies store at instantiation.
 type Opt func(*store)
 
@@ -35,3 +41,104 @@ func OptReturnASTValuesOnRead(enabled bool) Opt {
 		s.returnASTValuesOnRead = enabled
 	}
 }
+
+// OptTracer sets the OpenTelemetry tracer used to create spans for
+// NewTransaction, Read, Write and Commit. Spans are started from the context
+// passed to each operation and record the affected path, the number of
+// updates committed, and any error returned. When no tracer is set (the
+// default), no spans are created.
+func OptTracer(tracer trace.Tracer) Opt {
+	return func(s *store) {
+		s.tracer = tracer
+	}
+}
+
+// OptSlowTransactionThreshold enables reporting of slow store operations. When
+// a single Write, or a Commit including the execution of triggers, takes longer
+// than d, logf is invoked with the transaction id, the operation, the number of
+// paths affected and the elapsed time. Reporting is disabled if d is not
+// positive or logf is nil.
+func OptSlowTransactionThreshold(d time.Duration, logf func(format string, args ...any)) Opt {
+	return func(s *store) {
+		if d <= 0 || logf == nil {
+			s.slowTxnThreshold, s.slowTxnLogf = 0, nil
+			return
+		}
+		s.slowTxnThreshold, s.slowTxnLogf = d, logf
+	}
+}
+
+// OptAutoCreateParents sets whether an AddOp write into a path whose parent
+// objects do not exist creates the missing objects. By default the parent of
+// the written path must exist, as in JSON Patch, and a NotFoundErr is returned
+// otherwise. Missing array elements are never created.
+func OptAutoCreateParents(enabled bool) Opt {
+	return func(s *store) {
+		s.autoCreateParents = enabled
+	}
+}
+
+// OptPolicyValidation sets a function that is called with the id and contents
+// of every policy passed to UpsertPolicy. If it returns an error, the upsert is
+// rejected with that error and the transaction is left unchanged. Callers
+// typically pass a function that parses the policy, e.g., with ast.ParseModule.
+// By default, policies are stored without validation.
+func OptPolicyValidation(validate func(id string, bs []byte) error) Opt {
+	return func(s *store) {
+		s.validatePolicy = validate
+	}
+}
+
+// OptPolicyCompressor sets the Compressor used for policies held by the
+// store. Policies are compressed when they are upserted and decompressed when
+// read after the transaction that wrote them has been committed; triggers
+// always receive the uncompressed policy. By default, policies are stored
+// uncompressed, as with NoCompression.
+func OptPolicyCompressor(c Compressor) Opt {
+	return func(s *store) {
+		s.compressor = c
+	}
+}
+
+// OptMaxTriggerEventSize limits the number of data and policy changes included
+// in a single trigger event to n. When a transaction commits more changes than
+// that, the full event is never built: triggers registered with Chunked set
+// are invoked once per chunk of at most n changes, and other triggers are
+// invoked once with the first n changes and Truncated set. By default, events
+// are not limited.
+func OptMaxTriggerEventSize(n int) Opt {
+	return func(s *store) {
+		s.maxTriggerEventSize = n
+	}
+}
+
+// OptSortedPolicies sets whether ListPolicies returns policy ids in lexical
+// order, as the disk store does. By default, the order is unspecified and may
+// differ between calls.
+func OptSortedPolicies(enabled bool) Opt {
+	return func(s *store) {
+		s.sortedPolicies = enabled
+	}
+}
+
+// OptMaxNestingDepth limits the nesting depth of documents in the store to n
+// levels below the root. A write whose value, placed at the written path,
+// would nest deeper than that is rejected with an InvalidPatchErr and the
+// transaction is left unchanged. By default, nesting depth is unlimited.
+func OptMaxNestingDepth(n int) Opt {
+	return func(s *store) {
+		s.maxNestingDepth = n
+	}
+}
+
+// OptRawReadCacheSize sets the number of documents converted from AST values
+// by ReadRaw that are cached for reuse. It only has an effect when
+// OptReturnASTValuesOnRead is enabled. Cached documents are shared between
+// callers, which must not modify them, and the cache is dropped whenever a
+// transaction modifying data commits. By default, nothing is cached and every
+// ReadRaw converts the document.
+func OptRawReadCacheSize(n int) Opt {
+	return func(s *store) {
+		s.rawCacheSize = n
+	}
+}
@@ -42,10 +42,12 @@ type transaction struct {
 	xid      uint64
 	write    bool
 	stale    bool
+	unlocked bool // read transaction on a frozen store, rmu is not held
 }
 
 type policyUpdate struct {
 	value  []byte
+	stored []byte // value as compressed by the store's policy compressor
 	remove bool
 }
 
@@ -53,11 +55,22 @@ func (txn *transaction) ID() uint64 {
 	return txn.xid
 }
 
+func (txn *transaction) updateCount() int {
+	if txn.updates == nil {
+		return 0
+	}
+	return txn.updates.Len()
+}
+
 func (txn *transaction) Write(op storage.PatchOp, path storage.Path, value any) error {
 	if !txn.write {
 		return &storage.Error{Code: storage.InvalidTransactionErr, Message: "data write during read transaction"}
 	}
 
+	if maxDepth := txn.db.maxNestingDepth; maxDepth > 0 && op != storage.RemoveOp && len(path)+depth(value) > maxDepth {
+		return errors.NewInvalidPatchError("write to %v exceeds maximum nesting depth of %d", path, maxDepth)
+	}
+
 	if txn.updates == nil {
 		txn.updates = list.New()
 	}
@@ -188,29 +201,53 @@ func (txn *transaction) updateRoot(op storage.PatchOp, value any) error {
 	return nil
 }
 
-func (txn *transaction) Commit() (result storage.TriggerEvent) {
+func (txn *transaction) Commit() (storage.TriggerEvent, []storage.DataEvent) {
+	return txn.commit(len(txn.db.triggers) > 0)
+}
+
+// commit applies the transaction to the store, and returns the trigger event
+// describing the changes if collect is true. If collect is true and a trigger
+// asked for structural diffs, the data changes are also returned as diffs;
+// diff is nil otherwise.
+func (txn *transaction) commit(collect bool) (result storage.TriggerEvent, diff []storage.DataEvent) {
 	result.Context = txn.context
 
 	if txn.updates != nil {
-		if len(txn.db.triggers) > 0 {
+		if collect {
 			result.Data = slices.Grow(result.Data, txn.updates.Len())
+			if txn.db.wantsDiff() {
+				diff = make([]storage.DataEvent, 0, txn.updates.Len())
+			}
+		}
+		if txn.updates.Len() > 0 && txn.db.rawCacheSize > 0 {
+			txn.db.clearRawCache()
 		}
 
 		for curr := txn.updates.Front(); curr != nil; curr = curr.Next() {
 			action := curr.Value.(dataUpdate)
+
+			var old any
+			if diff != nil {
+				old = txn.db.diffBase(action)
+			}
+
 			txn.db.data = action.Apply(txn.db.data)
 
-			if len(txn.db.triggers) > 0 {
-				result.Data = append(result.Data, storage.DataEvent{
+			if collect {
+				event := storage.DataEvent{
 					Path:    action.Path(),
 					Data:    action.Value(),
 					Removed: action.Remove(),
-				})
+				}
+				result.Data = append(result.Data, event)
+				if diff != nil {
+					diff = appendDiff(diff, event, old)
+				}
 			}
 		}
 	}
 
-	if len(txn.policies) > 0 && len(txn.db.triggers) > 0 {
+	if len(txn.policies) > 0 && collect {
 		result.Policy = slices.Grow(result.Policy, len(txn.policies))
 	}
 
@@ -218,10 +255,10 @@ func (txn *transaction) Commit() (result storage.TriggerEvent) {
 		if upd.remove {
 			delete(txn.db.policies, id)
 		} else {
-			txn.db.policies[id] = upd.value
+			txn.db.policies[id] = upd.stored
 		}
 
-		if len(txn.db.triggers) > 0 {
+		if collect {
 			result.Policy = append(result.Policy, storage.PolicyEvent{
 				ID:      id,
 				Data:    upd.value,
@@ -229,7 +266,52 @@ func (txn *transaction) Commit() (result storage.TriggerEvent) {
 			})
 		}
 	}
-	return result
+	return result, diff
+}
+
+// eventChunks calls yield with trigger events describing the changes made by
+// the transaction, each containing at most n changes, until all changes have
+// been described or yield returns false.
+func (txn *transaction) eventChunks(n int, yield func(storage.TriggerEvent) bool) {
+	event := storage.TriggerEvent{Context: txn.context}
+	size := 0
+
+	flush := func() bool {
+		if size == 0 {
+			return true
+		}
+		ok := yield(event)
+		event = storage.TriggerEvent{Context: txn.context}
+		size = 0
+		return ok
+	}
+
+	if txn.updates != nil {
+		for curr := txn.updates.Front(); curr != nil; curr = curr.Next() {
+			action := curr.Value.(dataUpdate)
+			event.Data = append(event.Data, storage.DataEvent{
+				Path:    action.Path(),
+				Data:    action.Value(),
+				Removed: action.Remove(),
+			})
+			if size++; size == n && !flush() {
+				return
+			}
+		}
+	}
+
+	for id, upd := range txn.policies {
+		event.Policy = append(event.Policy, storage.PolicyEvent{
+			ID:      id,
+			Data:    upd.value,
+			Removed: upd.remove,
+		})
+		if size++; size == n && !flush() {
+			return
+		}
+	}
+
+	flush()
 }
 
 func pointer(v any, path storage.Path) (any, error) {
@@ -297,6 +379,32 @@ func (txn *transaction) Read(path storage.Path) (any, error) {
 	return cpy, nil
 }
 
+// Kind returns the kind of the document at path. Pending updates below path
+// only modify its descendants, so unlike Read, they need not be applied.
+func (txn *transaction) Kind(path storage.Path) (storage.Kind, error) {
+	data := txn.db.data
+	rel := path
+
+	if txn.write && txn.updates != nil {
+		for curr := txn.updates.Front(); curr != nil; curr = curr.Next() {
+			upd := curr.Value.(dataUpdate)
+			if path.HasPrefix(upd.Path()) {
+				if upd.Remove() {
+					return storage.KindInvalid, errors.NotFoundErr
+				}
+				data, rel = upd.Value(), path[len(upd.Path()):]
+				break
+			}
+		}
+	}
+
+	v, err := pointer(data, rel)
+	if err != nil {
+		return storage.KindInvalid, err
+	}
+	return storage.KindOf(v), nil
+}
+
 func (txn *transaction) ListPolicies() (ids []string) {
 	for id := range txn.db.policies {
 		if _, ok := txn.policies[id]; !ok {
@@ -308,6 +416,9 @@ func (txn *transaction) ListPolicies() (ids []string) {
 			ids = append(ids, id)
 		}
 	}
+	if txn.db.sortedPolicies {
+		slices.Sort(ids)
+	}
 	return ids
 }
 
@@ -321,17 +432,38 @@ func (txn *transaction) GetPolicy(id string) ([]byte, error) {
 		}
 	}
 	if exist, ok := txn.db.policies[id]; ok {
-		return exist, nil
+		return txn.db.decompressPolicy(id, exist)
 	}
 	return nil, errors.NewNotFoundErrorf("policy id %q", id)
 }
 
+// hasPolicy reports whether the policy exists, without decompressing it.
+func (txn *transaction) hasPolicy(id string) bool {
+	if update, ok := txn.policies[id]; ok {
+		return !update.remove
+	}
+	_, ok := txn.db.policies[id]
+	return ok
+}
+
 func (txn *transaction) UpsertPolicy(id string, bs []byte) error {
-	return txn.updatePolicy(id, policyUpdate{bs, false})
+	if !txn.write {
+		return &storage.Error{Code: storage.InvalidTransactionErr, Message: "policy write during read transaction"}
+	}
+	if txn.db.validatePolicy != nil {
+		if err := txn.db.validatePolicy(id, bs); err != nil {
+			return err
+		}
+	}
+	stored, err := txn.db.compressPolicy(id, bs)
+	if err != nil {
+		return err
+	}
+	return txn.updatePolicy(id, policyUpdate{value: bs, stored: stored})
 }
 
 func (txn *transaction) DeletePolicy(id string) error {
-	return txn.updatePolicy(id, policyUpdate{nil, true})
+	return txn.updatePolicy(id, policyUpdate{remove: true})
 }
 
 func (txn *transaction) updatePolicy(id string, update policyUpdate) error {
@@ -391,15 +523,67 @@ func (db *store) newUpdate(data any, op storage.PatchOp, path storage.Path, idx
 		if err != nil {
 			return nil, err
 		}
+		if db.autoCreateParents && op == storage.AddOp {
+			path, value = createParents(astData, path, idx, value)
+		}
 		astValue, err := ast.InterfaceToValue(value)
 		if err != nil {
 			return nil, err
 		}
 		return newUpdateAST(astData, op, path, idx, astValue)
 	}
+	if db.autoCreateParents && op == storage.AddOp {
+		path, value = createParents(data, path, idx, value)
+	}
 	return newUpdateRaw(data, op, path, idx, value)
 }
 
+// createParents looks for the first object along path, starting at idx, that
+// does not exist in data. If found, the add of value at path is rewritten into
+// an add at the missing object, with value nested under the remainder of path.
+// Otherwise, or if the walk fails for any other reason, path and value are
+// returned unchanged and the update reports the error as usual.
+func createParents(data any, path storage.Path, idx int, value any) (storage.Path, any) {
+	for i := idx; i < len(path)-1; i++ {
+		switch node := data.(type) {
+		case map[string]any:
+			child, ok := node[path[i]]
+			if !ok {
+				return path[:i+1], nestValue(path[i+1:], value)
+			}
+			data = child
+		case ast.Object:
+			child := node.Get(ast.InternedTerm(path[i]))
+			if child == nil {
+				return path[:i+1], nestValue(path[i+1:], value)
+			}
+			data = child.Value
+		case []any:
+			pos, err := ptr.ValidateArrayIndex(node, path[i], path)
+			if err != nil {
+				return path, value
+			}
+			data = node[pos]
+		case *ast.Array:
+			pos, err := ptr.ValidateASTArrayIndex(node, path[i], path)
+			if err != nil {
+				return path, value
+			}
+			data = node.Elem(pos).Value
+		default:
+			return path, value
+		}
+	}
+	return path, value
+}
+
+func nestValue(path storage.Path, value any) any {
+	for i := len(path) - 1; i >= 0; i-- {
+		value = map[string]any{path[i]: value}
+	}
+	return value
+}
+
 func newUpdateRaw(data any, op storage.PatchOp, path storage.Path, idx int, value any) (dataUpdate, error) {
 	switch data.(type) {
 	case nil, bool, json.Number, string:
@@ -43,6 +43,12 @@ type Store interface {
 	Abort(context.Context, Transaction)
 }
 
+// Factory defines the interface for creating stores, which allows components
+// to be configured with a backend without depending on it.
+type Factory interface {
+	New() Store
+}
+
 // MakeDirer defines the interface a Store could realize to override the
 // generic MakeDir functionality in storage.MakeDir
 type MakeDirer interface {
@@ -54,6 +60,20 @@ type NonEmptyer interface {
 	NonEmpty(context.Context, Transaction) func([]string) (bool, error)
 }
 
+// RawReader defines the interface a Store could realize to override the
+// generic conversion of AST values in storage.ReadRaw, e.g., to cache the
+// converted documents.
+type RawReader interface {
+	ReadRaw(context.Context, Transaction, Path) (any, error)
+}
+
+// Freezer defines the interface a Store could realize to support being made
+// immutable. Once Freeze returns, write transactions are rejected with a
+// FrozenErr. Frozen stores cannot be unfrozen.
+type Freezer interface {
+	Freeze()
+}
+
 // TransactionParams describes a new transaction.
 type TransactionParams struct {
 
@@ -190,6 +210,12 @@ type TriggerEvent struct {
 	Policy  []PolicyEvent
 	Data    []DataEvent
 	Context *Context
+
+	// Truncated is true if the event only describes some of the changes
+	// made by the transaction, because the store limits the size of events.
+	// Triggers receiving a truncated event should not rely on Policy and Data
+	// to be complete, and re-read the store instead.
+	Truncated bool
 }
 
 // IsZero returns true if the TriggerEvent indicates no changes occurred. This
@@ -219,6 +245,19 @@ type TriggerConfig struct {
 	// callback is invoked with a handle to the write transaction that
 	// successfully committed before other clients see the changes.
 	OnCommit func(context.Context, Transaction, TriggerEvent)
+
+	// Chunked when set to true, allows the store to invoke OnCommit several
+	// times for a single transaction, each time with a part of the changes,
+	// if the store limits the size of events. Otherwise, such triggers are
+	// invoked once with a truncated event.
+	Chunked bool
+
+	// Diff when set to true, asks the store to describe data changes in the
+	// events passed to OnCommit as the minimal structural changes computed
+	// with DiffToPatch, rather than the whole value written at each path.
+	// Each DataEvent then names a changed sub-path. Stores that cannot
+	// compute diffs for a change pass the coarse DataEvent instead.
+	Diff bool
 }
 
 // Trigger defines the interface that stores implement to register for change
@@ -17,12 +17,14 @@ const (
 	OutOfRangeMsg          = "array index out of range"
 	RootMustBeObjectMsg    = "root must be object"
 	RootCannotBeRemovedMsg = "root cannot be removed"
+	FrozenMsg              = "store is frozen"
 )
 
 var (
 	NotFoundErr            = &storage.Error{Code: storage.NotFoundErr, Message: DoesNotExistMsg}
 	RootMustBeObjectErr    = &storage.Error{Code: storage.InvalidPatchErr, Message: RootMustBeObjectMsg}
 	RootCannotBeRemovedErr = &storage.Error{Code: storage.InvalidPatchErr, Message: RootCannotBeRemovedMsg}
+	FrozenErr              = &storage.Error{Code: storage.FrozenErr, Message: FrozenMsg}
 )
 
 func NewNotFoundErrorWithHint(path storage.Path, hint string) *storage.Error {
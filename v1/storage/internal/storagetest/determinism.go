@@ -0,0 +1,54 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package storagetest contains conformance tests shared by the storage
+// backends.
+package storagetest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+)
+
+var determinismPolicies = []string{"z.rego", "a.rego", "m/n.rego", "b.rego"}
+
+// RunDeterminism checks that stores created by f list policies in lexical
+// order, regardless of the order in which they were written, and that the
+// order is the same on every call. Only orderings that backends produce
+// themselves are checked: storage.ListKeys and encoding/json sort their
+// output, so they agree across backends by construction.
+func RunDeterminism(t *testing.T, f storage.Factory) {
+	t.Helper()
+
+	ctx := t.Context()
+	store := f.New()
+
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		for _, id := range determinismPolicies {
+			if err := store.UpsertPolicy(ctx, txn, id, []byte("package x")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	exp := slices.Sorted(slices.Values(determinismPolicies))
+	for range 3 {
+		act, err := store.ListPolicies(ctx, txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(exp, act) {
+			t.Fatalf("expected policies %v but got %v", exp, act)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// Kind is the JSON type of a stored document.
+type Kind int
+
+const (
+	// KindInvalid is returned for values that are not JSON documents.
+	KindInvalid Kind = iota
+	KindNull
+	KindBoolean
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNull:
+		return "null"
+	case KindBoolean:
+		return "boolean"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindArray:
+		return "array"
+	case KindObject:
+		return "object"
+	default:
+		return "invalid"
+	}
+}
+
+// Kinder defines the interface a Store could realize to override the generic
+// implementation of TypeOf, e.g., to report the kind of a document without
+// materializing it.
+type Kinder interface {
+	Kind(context.Context, Transaction, Path) (Kind, error)
+}
+
+// TypeOf returns the kind of the document at path. Stores implementing Kinder
+// report it directly; for other stores the document is read, which may decode
+// or build it.
+func TypeOf(ctx context.Context, store Store, txn Transaction, path Path) (Kind, error) {
+	if k, ok := store.(Kinder); ok {
+		return k.Kind(ctx, txn, path)
+	}
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return KindInvalid, err
+	}
+	return KindOf(v), nil
+}
+
+// KindOf returns the kind of a document represented by native Go values or
+// AST values.
+func KindOf(v any) Kind {
+	switch v.(type) {
+	case nil, ast.Null:
+		return KindNull
+	case bool, ast.Boolean:
+		return KindBoolean
+	case json.Number, ast.Number:
+		return KindNumber
+	case string, ast.String:
+		return KindString
+	case []any, *ast.Array:
+		return KindArray
+	case map[string]any, ast.Object:
+		return KindObject
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return KindNumber
+	}
+	return KindInvalid
+}
@@ -0,0 +1,89 @@
+package storage_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestTypeOf(t *testing.T) {
+	data := `{"o": {}, "a": [], "s": "x", "n": 1.5, "b": false, "z": null}`
+
+	exp := map[string]storage.Kind{
+		"/":  storage.KindObject,
+		"/o": storage.KindObject,
+		"/a": storage.KindArray,
+		"/s": storage.KindString,
+		"/n": storage.KindNumber,
+		"/b": storage.KindBoolean,
+		"/z": storage.KindNull,
+	}
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			for path, kind := range exp {
+				act, err := storage.TypeOf(ctx, store, txn, storage.MustParsePath(path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if act != kind {
+					t.Errorf("%s: expected %v but got %v", path, kind, act)
+				}
+			}
+
+			if _, err := storage.TypeOf(ctx, store, txn, storage.MustParsePath("/missing")); !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestTypeOfPendingWrites(t *testing.T) {
+	ctx := t.Context()
+	store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"o": {"x": "s"}, "r": {}}`), inmem.OptRoundTripOnWrite(false))
+	if _, ok := store.(storage.Kinder); !ok {
+		t.Fatal("expected inmem store to implement storage.Kinder")
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	defer store.Abort(ctx, txn)
+
+	writes := map[string]any{"/o/i": int32(3), "/o/u": uint(4), "/o/f": float32(1.5), "/o/x": []any{}}
+	for path, v := range writes {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath(path), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Write(ctx, txn, storage.RemoveOp, storage.MustParsePath("/r"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]storage.Kind{
+		"/o":   storage.KindObject,
+		"/o/i": storage.KindNumber,
+		"/o/u": storage.KindNumber,
+		"/o/f": storage.KindNumber,
+		"/o/x": storage.KindArray,
+	}
+	for path, kind := range exp {
+		act, err := storage.TypeOf(ctx, store, txn, storage.MustParsePath(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act != kind {
+			t.Errorf("%s: expected %v but got %v", path, kind, act)
+		}
+	}
+
+	if _, err := storage.TypeOf(ctx, store, txn, storage.MustParsePath("/r")); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error for removed document but got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/internal/merge"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// LoadDir loads the data and policy files found under dir into store using a
+// single write transaction.
+//
+// Files with a .json, .yaml or .yml extension are parsed as data and placed
+// at the path of the directory containing them, relative to dir, following the
+// same convention as bundles: dir/a/b/data.json is loaded at /a/b. Data files
+// found in the same directory are merged and must not conflict. Each resulting
+// top-level document is written with AddOp, replacing any existing document
+// under the same key.
+//
+// Files with a .rego extension are upserted as policies, using their slash
+// separated path relative to dir as the policy id. Other files are ignored.
+func LoadDir(ctx context.Context, store Store, dir string) error {
+	data := map[string]any{}
+	policies := map[string][]byte{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch filepath.Ext(path) {
+		case ".rego":
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			policies[rel] = bs
+		case ".json", ".yaml", ".yml":
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			var value any
+			if err := util.Unmarshal(bs, &value); err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+
+			var key []string
+			if dirpath := filepath.ToSlash(filepath.Dir(rel)); dirpath != "." {
+				key = strings.Split(dirpath, "/")
+			}
+
+			obj, ok := makeTree(key, value)
+			if !ok {
+				return fmt.Errorf("%s: root document must be an object", rel)
+			}
+
+			if data, ok = merge.InterfaceMaps(data, obj); !ok {
+				return fmt.Errorf("%s: conflicts with previously loaded data", rel)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return Txn(ctx, store, WriteParams, func(txn Transaction) error {
+		for k, v := range data {
+			if err := store.Write(ctx, txn, AddOp, Path{k}, v); err != nil {
+				return err
+			}
+		}
+		for id, bs := range policies {
+			if err := store.UpsertPolicy(ctx, txn, id, bs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// makeTree nests value under path. For an empty path, value must be an object.
+func makeTree(path []string, value any) (map[string]any, bool) {
+	if len(path) == 0 {
+		obj, ok := value.(map[string]any)
+		return obj, ok
+	}
+
+	for i := len(path) - 1; i > 0; i-- {
+		value = map[string]any{path[i]: value}
+	}
+
+	return map[string]any{path[0]: value}, true
+}
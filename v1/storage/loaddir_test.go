@@ -0,0 +1,104 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestLoadDir(t *testing.T) {
+	files := map[string]string{
+		"data.json":                 `{"top": true}`,
+		"users/data.json":           `{"alice": {"role": "admin"}}`,
+		"users/more.yaml":           "bob:\n  role: dev\n",
+		"config/limits/data.yml":    "rps: 10\n",
+		"policies/authz.rego":       "package authz\n",
+		"policies/nested/rbac.rego": "package rbac\n",
+		"README.md":                 "ignored",
+	}
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := t.Context()
+	store := inmem.New()
+
+	if err := storage.LoadDir(ctx, store, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var exp any
+	if err := util.UnmarshalJSON([]byte(`{
+		"top": true,
+		"users": {"alice": {"role": "admin"}, "bob": {"role": "dev"}},
+		"config": {"limits": {"rps": 10}}
+	}`), &exp); err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := storage.ReadOne(ctx, store, storage.RootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %v but got %v", exp, act)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(ids)
+
+	if expIDs := []string{"policies/authz.rego", "policies/nested/rbac.rego"}; !slices.Equal(expIDs, ids) {
+		t.Fatalf("expected policies %v but got %v", expIDs, ids)
+	}
+
+	bs, err := store.GetPolicy(ctx, txn, "policies/nested/rbac.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != files["policies/nested/rbac.rego"] {
+		t.Fatalf("unexpected policy content: %q", bs)
+	}
+}
+
+func TestLoadDirConflict(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"x": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"x": 2}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := t.Context()
+	store := inmem.New()
+
+	if err := storage.LoadDir(ctx, store, dir); err == nil {
+		t.Fatal("expected conflict error")
+	}
+
+	// Nothing is written when loading fails.
+	if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/x")); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// CollectPolicyMetadata parses every policy stored in store and returns the
+// parser metadata of all of them merged together. This makes it possible to
+// answer questions like "which built-in functions are used" or "do any
+// policies call print" without compiling the policies.
+//
+// Only the capabilities, future keywords and Rego version set in popts are
+// taken into account. An error is returned if any of the policies fails to
+// parse.
+func CollectPolicyMetadata(ctx context.Context, store Store, txn Transaction, popts ast.ParserOptions) (*ast.ParserMetadata, error) {
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+
+	result := &ast.ParserMetadata{}
+	for _, id := range ids {
+		bs, err := store.GetPolicy(ctx, txn, id)
+		if err != nil {
+			return nil, err
+		}
+
+		parser := ast.NewParser().
+			WithFilename(id).
+			WithReader(bytes.NewReader(bs)).
+			WithCapabilities(popts.Capabilities).
+			WithFutureKeywords(popts.FutureKeywords...).
+			WithAllFutureKeywords(popts.AllFutureKeywords).
+			WithRegoVersion(popts.RegoVersion).
+			WithCollectMetadata(true)
+
+		if _, _, errs := parser.Parse(); len(errs) > 0 {
+			return nil, fmt.Errorf("%s: %w", id, errs)
+		}
+
+		result.Merge(parser.Metadata())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,74 @@
+package storage_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestCollectPolicyMetadata(t *testing.T) {
+	policies := map[string]string{
+		"a.rego": "package a\n\nallow if { print(input.user); startswith(input.user, \"admin\") }\n",
+		"b.rego": "package b\n\nnames := {upper(x) | some x in data.users}\n",
+		"c.rego": "package c\n\ndeny if print(\"deny\")\n",
+	}
+
+	ctx := t.Context()
+	store := inmem.New()
+
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		for id, src := range policies {
+			if err := store.UpsertPolicy(ctx, txn, id, []byte(src)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	md, err := storage.CollectPolicyMetadata(ctx, store, txn, ast.ParserOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []string{"assign", "internal.member_2", "print", "startswith", "upper"}
+	if act := md.Builtins(); !slices.Equal(exp, act) {
+		t.Fatalf("expected builtins %v but got %v", exp, act)
+	}
+	if n := md.PrintCallCount(); n != 2 {
+		t.Fatalf("expected 2 print calls but got %d", n)
+	}
+	if _, set, _ := md.ComprehensionCounts(); set != 1 {
+		t.Fatalf("expected 1 set comprehension but got %d", set)
+	}
+	if n := md.RuleHeadCounts()[ast.CompleteRuleHead]; n != 3 {
+		t.Fatalf("expected 3 complete rules but got %d", n)
+	}
+}
+
+func TestCollectPolicyMetadataParseError(t *testing.T) {
+	ctx := t.Context()
+	store := inmem.New()
+
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.UpsertPolicy(ctx, txn, "bad.rego", []byte("package"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if _, err := storage.CollectPolicyMetadata(ctx, store, txn, ast.ParserOptions{}); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
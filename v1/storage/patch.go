@@ -0,0 +1,74 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"strconv"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// Patch describes a single write operation on a store.
+type Patch struct {
+	Op    PatchOp
+	Path  Path
+	Value any
+}
+
+// DiffToPatch returns the patches that transform the document current into
+// target when written, in order, with Store.Write at the path both documents
+// are located at. Objects are compared key by key, so only added, removed and
+// changed keys produce patches. Arrays of the same length are compared element
+// by element; arrays of different lengths, and values of different types, are
+// replaced entirely. Values are compared as in Test, so 1 and 1.0 are equal.
+//
+// Patches for object keys are sorted by key, making the result deterministic.
+// DiffToPatch returns nil if the documents are equal.
+func DiffToPatch(current, target any) []Patch {
+	return diffToPatch(nil, Path{}, current, target)
+}
+
+func diffToPatch(patches []Patch, path Path, current, target any) []Patch {
+	switch c := current.(type) {
+	case map[string]any:
+		t, ok := target.(map[string]any)
+		if !ok {
+			break
+		}
+		for _, k := range util.KeysSorted(c) {
+			if _, ok := t[k]; !ok {
+				patches = append(patches, Patch{Op: RemoveOp, Path: childPath(path, k)})
+			}
+		}
+		for _, k := range util.KeysSorted(t) {
+			if cv, ok := c[k]; ok {
+				patches = diffToPatch(patches, childPath(path, k), cv, t[k])
+			} else {
+				patches = append(patches, Patch{Op: AddOp, Path: childPath(path, k), Value: t[k]})
+			}
+		}
+		return patches
+	case []any:
+		t, ok := target.([]any)
+		if !ok || len(t) != len(c) {
+			break
+		}
+		for i := range c {
+			patches = diffToPatch(patches, childPath(path, strconv.Itoa(i)), c[i], t[i])
+		}
+		return patches
+	}
+
+	if eq, err := valueEqual(current, target); err == nil && eq {
+		return patches
+	}
+	return append(patches, Patch{Op: ReplaceOp, Path: path, Value: target})
+}
+
+func childPath(path Path, key string) Path {
+	child := make(Path, len(path), len(path)+1)
+	copy(child, path)
+	return append(child, key)
+}
@@ -0,0 +1,114 @@
+package storage_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestDiffToPatch(t *testing.T) {
+	tests := []struct {
+		note    string
+		current string
+		target  string
+		exp     []storage.Patch
+	}{
+		{
+			note:    "equal",
+			current: `{"a": {"b": [1, 2]}, "c": 1}`,
+			target:  `{"a": {"b": [1, 2]}, "c": 1.0}`,
+		},
+		{
+			note:    "nested object",
+			current: `{"a": {"keep": 1, "change": "x", "drop": true}}`,
+			target:  `{"a": {"keep": 1, "change": "y", "new": [1]}}`,
+			exp: []storage.Patch{
+				{Op: storage.RemoveOp, Path: storage.MustParsePath("/a/drop")},
+				{Op: storage.ReplaceOp, Path: storage.MustParsePath("/a/change"), Value: "y"},
+				{Op: storage.AddOp, Path: storage.MustParsePath("/a/new"), Value: []any{1}},
+			},
+		},
+		{
+			note:    "array element",
+			current: `{"xs": [1, {"k": 1}]}`,
+			target:  `{"xs": [1, {"k": 2}]}`,
+			exp: []storage.Patch{
+				{Op: storage.ReplaceOp, Path: storage.MustParsePath("/xs/1/k"), Value: 2},
+			},
+		},
+		{
+			note:    "array length",
+			current: `{"xs": [1]}`,
+			target:  `{"xs": [1, 2]}`,
+			exp: []storage.Patch{
+				{Op: storage.ReplaceOp, Path: storage.MustParsePath("/xs"), Value: []any{1, 2}},
+			},
+		},
+		{
+			note:    "type change",
+			current: `{"a": {"b": 1}}`,
+			target:  `{"a": "b"}`,
+			exp: []storage.Patch{
+				{Op: storage.ReplaceOp, Path: storage.MustParsePath("/a"), Value: "b"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var current, target any
+			if err := util.UnmarshalJSON([]byte(tc.current), &current); err != nil {
+				t.Fatal(err)
+			}
+			if err := util.UnmarshalJSON([]byte(tc.target), &target); err != nil {
+				t.Fatal(err)
+			}
+
+			act := storage.DiffToPatch(current, target)
+			if len(act) != len(tc.exp) {
+				t.Fatalf("expected %v but got %v", tc.exp, act)
+			}
+			for i := range act {
+				if act[i].Op != tc.exp[i].Op || !act[i].Path.Equal(tc.exp[i].Path) || !valuesEqual(t, act[i].Value, tc.exp[i].Value) {
+					t.Fatalf("expected %v but got %v", tc.exp, act)
+				}
+			}
+
+			// Applying the patches to a store holding current yields target.
+			ctx := t.Context()
+			store := inmem.NewFromReader(strings.NewReader(tc.current))
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				for _, p := range act {
+					if err := store.Write(ctx, txn, p.Op, p.Path, p.Value); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := storage.ReadOne(ctx, store, storage.RootPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !valuesEqual(t, target, result) {
+				t.Fatalf("expected %v after applying patches but got %v", target, result)
+			}
+		})
+	}
+}
+
+func valuesEqual(t *testing.T, a, b any) bool {
+	t.Helper()
+	if a == nil || b == nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return ast.MustInterfaceToValue(a).Compare(ast.MustInterfaceToValue(b)) == 0
+}
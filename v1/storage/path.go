@@ -5,6 +5,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -47,6 +48,44 @@ func ParsePathEscaped(str string) (path Path, ok bool) {
 	return
 }
 
+// ParseJSONPointer returns a new path for the given RFC 6901 JSON Pointer. The
+// empty pointer refers to the root document. Non-empty pointers must start
+// with "/", and "~1" and "~0" in reference tokens are decoded to "/" and "~"
+// respectively; any other use of "~" is an error.
+func ParseJSONPointer(s string) (Path, error) {
+	if s == "" {
+		return Path{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with /", s)
+	}
+
+	path := strings.Split(s[1:], "/")
+	for i, token := range path {
+		if !strings.Contains(token, "~") {
+			continue
+		}
+		var sb strings.Builder
+		for j := 0; j < len(token); j++ {
+			if token[j] != '~' {
+				sb.WriteByte(token[j])
+				continue
+			}
+			if j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("invalid JSON pointer %q: ~ must be followed by 0 or 1", s)
+			}
+			if token[j+1] == '0' {
+				sb.WriteByte('~')
+			} else {
+				sb.WriteByte('/')
+			}
+			j++
+		}
+		path[i] = sb.String()
+	}
+	return path, nil
+}
+
 // NewPathForRef returns a new path for the given ref.
 func NewPathForRef(ref ast.Ref) (path Path, err error) {
 	if len(ref) == 0 {
@@ -131,6 +170,46 @@ func (p Path) String() string {
 	return sb.String()
 }
 
+// JSONPointer returns p as an RFC 6901 JSON Pointer. The root path is returned
+// as the empty pointer.
+func (p Path) JSONPointer() string {
+	var sb strings.Builder
+	for i := range p {
+		sb.WriteByte('/')
+		sb.WriteString(jsonPointerEscaper.Replace(p[i]))
+	}
+	return sb.String()
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// MarshalJSON returns p as a JSON string in its escaped form, as returned by
+// String.
+func (p Path) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON parses a JSON string containing an escaped path, as produced
+// by MarshalJSON. For compatibility with paths serialized before Path had a
+// JSON representation, an array of unescaped segments is also accepted.
+func (p *Path) UnmarshalJSON(bs []byte) error {
+	var s string
+	if err := json.Unmarshal(bs, &s); err != nil {
+		var segments []string
+		if json.Unmarshal(bs, &segments) != nil {
+			return fmt.Errorf("invalid path: expected string but got %s", bs)
+		}
+		*p = segments
+		return nil
+	}
+	path, ok := ParsePathEscaped(s)
+	if !ok {
+		return fmt.Errorf("invalid path: %q", s)
+	}
+	*p = path
+	return nil
+}
+
 // MustParsePath returns a new Path for s. If s cannot be parsed, this function
 // will panic. This is mostly for test purposes.
 func MustParsePath(s string) Path {
@@ -140,3 +219,66 @@ func MustParsePath(s string) Path {
 	}
 	return path
 }
+
+// ValidatePath checks that path can be resolved in schema, a document
+// represented by native Go values or AST values, without reading a store. An
+// error with the NotFoundErr code is returned for the first segment that does
+// not exist, and an error with the TypeMismatchErr code if a segment is applied
+// to a value that is neither an object nor an array.
+func ValidatePath(schema any, path Path) error {
+	node := schema
+	for i, key := range path {
+		switch v := node.(type) {
+		case map[string]any:
+			child, ok := v[key]
+			if !ok {
+				return missingSegmentError(path, i, "does not exist")
+			}
+			node = child
+		case ast.Object:
+			child := v.Get(ast.InternedTerm(key))
+			if child == nil {
+				return missingSegmentError(path, i, "does not exist")
+			}
+			node = child.Value
+		case []any:
+			idx, reason := validateArrayIndex(key, len(v))
+			if reason != "" {
+				return missingSegmentError(path, i, reason)
+			}
+			node = v[idx]
+		case *ast.Array:
+			idx, reason := validateArrayIndex(key, v.Len())
+			if reason != "" {
+				return missingSegmentError(path, i, reason)
+			}
+			node = v.Elem(idx).Value
+		default:
+			return &Error{
+				Code:    TypeMismatchErr,
+				Message: fmt.Sprintf("%v: expected object or array at %v but got %v", path, path[:i], typeName(node)),
+			}
+		}
+	}
+	return nil
+}
+
+// validateArrayIndex returns the index denoted by key in an array of length n,
+// or the reason why key is not a valid index.
+func validateArrayIndex(key string, n int) (int, string) {
+	idx, err := strconv.Atoi(key)
+	if err != nil {
+		return 0, "is not an array index"
+	}
+	if idx < 0 || idx >= n {
+		return 0, "is out of range"
+	}
+	return idx, ""
+}
+
+func missingSegmentError(path Path, i int, reason string) *Error {
+	return &Error{
+		Code:    NotFoundErr,
+		Message: fmt.Sprintf("%v: %v %v", path, path[:i+1], reason),
+	}
+}
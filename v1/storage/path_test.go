@@ -5,6 +5,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"errors"
 	"math"
 	"testing"
@@ -109,6 +110,88 @@ func TestNewPathForStringEscaped(t *testing.T) {
 	}
 }
 
+func TestPathJSON(t *testing.T) {
+	tests := []struct {
+		path Path
+		json string
+	}{
+		{path: Path{}, json: `"/"`},
+		{path: Path{"foo", "bar"}, json: `"/foo/bar"`},
+		{path: Path{"foo/bar", "baz"}, json: `"/foo%2Fbar/baz"`},
+		{path: Path{"with space", "0"}, json: `"/with%20space/0"`},
+	}
+
+	for _, tc := range tests {
+		bs, err := json.Marshal(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != tc.json {
+			t.Errorf("expected %v to marshal to %s but got %s", tc.path, tc.json, bs)
+		}
+
+		var result Path
+		if err := json.Unmarshal(bs, &result); err != nil {
+			t.Fatal(err)
+		}
+		if !result.Equal(tc.path) {
+			t.Errorf("expected %s to unmarshal to %v but got %v", bs, tc.path, result)
+		}
+	}
+
+	var result Path
+	if err := json.Unmarshal([]byte(`["foo/bar", "baz"]`), &result); err != nil || !result.Equal(Path{"foo/bar", "baz"}) {
+		t.Errorf("expected segment array to unmarshal but got %v (err: %v)", result, err)
+	}
+
+	for _, bad := range []string{`"foo"`, `"/foo%%%%bar"`, `42`, `{}`} {
+		if err := json.Unmarshal([]byte(bad), &result); err == nil {
+			t.Errorf("expected error unmarshaling %s", bad)
+		}
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	// Examples from RFC 6901, section 5.
+	tests := []struct {
+		pointer string
+		path    Path
+	}{
+		{pointer: "", path: Path{}},
+		{pointer: "/foo", path: Path{"foo"}},
+		{pointer: "/foo/0", path: Path{"foo", "0"}},
+		{pointer: "/", path: Path{""}},
+		{pointer: "/a~1b", path: Path{"a/b"}},
+		{pointer: "/c%d", path: Path{"c%d"}},
+		{pointer: "/e^f", path: Path{"e^f"}},
+		{pointer: "/g|h", path: Path{"g|h"}},
+		{pointer: "/i\\j", path: Path{"i\\j"}},
+		{pointer: "/k\"l", path: Path{"k\"l"}},
+		{pointer: "/ ", path: Path{" "}},
+		{pointer: "/m~0n", path: Path{"m~n"}},
+		{pointer: "/~01", path: Path{"~1"}},
+	}
+
+	for _, tc := range tests {
+		path, err := ParseJSONPointer(tc.pointer)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.pointer, err)
+		}
+		if !path.Equal(tc.path) {
+			t.Errorf("%q: expected %#v but got %#v", tc.pointer, tc.path, path)
+		}
+		if act := tc.path.JSONPointer(); act != tc.pointer {
+			t.Errorf("%#v: expected pointer %q but got %q", tc.path, tc.pointer, act)
+		}
+	}
+
+	for _, bad := range []string{"foo", "/a~", "/a~2b"} {
+		if _, err := ParseJSONPointer(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
 func TestPathCompare(t *testing.T) {
 	tests := []struct {
 		a      Path
@@ -214,3 +297,43 @@ func BenchmarkPathString(b *testing.B) {
 		}
 	}
 }
+
+func TestValidatePath(t *testing.T) {
+	schema := map[string]any{
+		"users": map[string]any{
+			"alice": map[string]any{"roles": []any{"admin"}},
+		},
+		"name": "x",
+	}
+
+	tests := []struct {
+		path  string
+		check func(error) bool
+		msg   string
+	}{
+		{path: "/users/alice/roles/0"},
+		{path: "/"},
+		{path: "/name/first", check: IsTypeMismatch, msg: "/name/first: expected object or array at /name but got string"},
+		{path: "/users/bob/roles", check: IsNotFound, msg: "/users/bob/roles: /users/bob does not exist"},
+		{path: "/users/alice/roles/1", check: IsNotFound, msg: "/users/alice/roles/1: /users/alice/roles/1 is out of range"},
+		{path: "/users/alice/roles/x", check: IsNotFound, msg: "/users/alice/roles/x: /users/alice/roles/x is not an array index"},
+	}
+
+	for _, value := range []any{schema, ast.MustInterfaceToValue(schema)} {
+		for _, tc := range tests {
+			err := ValidatePath(value, MustParsePath(tc.path))
+			if tc.check == nil {
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", tc.path, err)
+				}
+				continue
+			}
+			if !tc.check(err) {
+				t.Fatalf("%s: unexpected error: %v", tc.path, err)
+			}
+			if err.(*Error).Message != tc.msg {
+				t.Fatalf("%s: expected message %q but got %q", tc.path, tc.msg, err.(*Error).Message)
+			}
+		}
+	}
+}
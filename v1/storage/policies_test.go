@@ -0,0 +1,69 @@
+package storage_test
+
+import (
+	"bytes"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+func TestExportImportPolicies(t *testing.T) {
+	ctx := t.Context()
+	policies := map[string][]byte{
+		"authz.rego":       []byte("package authz\n\nallow := true\n"),
+		"nested/rbac.rego": []byte("package rbac\n"),
+		"binary":           {0xff, 0x00, 0xfe},
+	}
+
+	src := inmem.NewFromReader(strings.NewReader(`{"data": "not exported"}`))
+	if err := storage.Txn(ctx, src, storage.WriteParams, func(txn storage.Transaction) error {
+		for id, bs := range policies {
+			if err := src.UpsertPolicy(ctx, txn, id, bs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := storage.Txn(ctx, src, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		return storage.ExportPolicies(ctx, src, txn, &buf)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := inmem.New()
+	if err := storage.Txn(ctx, dst, storage.WriteParams, func(txn storage.Transaction) error {
+		return storage.ImportPolicies(ctx, dst, txn, &buf)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, dst)
+	defer dst.Abort(ctx, txn)
+
+	ids, err := dst.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		if act[id], err = dst.GetPolicy(ctx, txn, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !maps.EqualFunc(policies, act, bytes.Equal) {
+		t.Fatalf("expected policies %v but got %v", policies, act)
+	}
+
+	if _, err := dst.Read(ctx, txn, storage.MustParsePath("/data")); !storage.IsNotFound(err) {
+		t.Fatalf("expected data not to be exported, got %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// ReadToDepth reads the document at path, expanding at most depth levels of
+// nested objects and arrays. Objects and arrays found below that depth are
+// replaced with a summary object of the form:
+//
+//	{"type": "object", "size": <number of keys>}
+//	{"type": "array", "size": <number of elements>}
+//
+// A depth of zero summarizes the document at path itself if it is an object or
+// array. A negative depth disables truncation.
+func ReadToDepth(ctx context.Context, store Store, txn Transaction, path Path, depth int) (any, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	if depth < 0 {
+		return v, nil
+	}
+	return truncateDepth(v, depth), nil
+}
+
+func truncateDepth(v any, depth int) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if depth == 0 {
+			return depthSummary("object", len(v))
+		}
+		obj := make(map[string]any, len(v))
+		for k, x := range v {
+			obj[k] = truncateDepth(x, depth-1)
+		}
+		return obj
+	case []any:
+		if depth == 0 {
+			return depthSummary("array", len(v))
+		}
+		arr := make([]any, len(v))
+		for i, x := range v {
+			arr[i] = truncateDepth(x, depth-1)
+		}
+		return arr
+	}
+	return v
+}
+
+func depthSummary(typ string, size int) map[string]any {
+	return map[string]any{
+		"type": typ,
+		"size": json.Number(strconv.Itoa(size)),
+	}
+}
+
+// ReadExcluding reads the document at path, omitting object fields whose key
+// is in excludeFields at every level of nesting, including objects nested in
+// arrays. The store is not modified.
+func ReadExcluding(ctx context.Context, store Store, txn Transaction, path Path, excludeFields []string) (any, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(excludeFields) == 0 {
+		return v, nil
+	}
+	exclude := make(map[string]struct{}, len(excludeFields))
+	for _, f := range excludeFields {
+		exclude[f] = struct{}{}
+	}
+	return excludeKeys(v, exclude), nil
+}
+
+func excludeKeys(v any, exclude map[string]struct{}) any {
+	switch v := v.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(v))
+		for k, x := range v {
+			if _, ok := exclude[k]; !ok {
+				obj[k] = excludeKeys(x, exclude)
+			}
+		}
+		return obj
+	case []any:
+		arr := make([]any, len(v))
+		for i, x := range v {
+			arr[i] = excludeKeys(x, exclude)
+		}
+		return arr
+	}
+	return v
+}
+
+// ReadMasked reads the document at path, keeping only the documents at the
+// paths in allow, which are relative to path, and the objects and arrays
+// leading to them. Everything else is omitted. Array elements are matched by
+// index, and kept elements retain their relative order. Allowed paths that do
+// not exist are ignored. If allow contains the empty path, the whole document
+// is returned; otherwise nil is returned for scalar documents. The store is
+// not modified.
+func ReadMasked(ctx context.Context, store Store, txn Transaction, path Path, allow []Path) (any, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	m := &mask{}
+	for _, p := range allow {
+		m.insert(p)
+	}
+	if v, ok := m.apply(v); ok {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// mask is a tree of allowed paths. A node with all set keeps the whole
+// document below it.
+type mask struct {
+	all      bool
+	children map[string]*mask
+}
+
+func (m *mask) insert(p Path) {
+	for _, s := range p {
+		if m.all {
+			return
+		}
+		if m.children == nil {
+			m.children = map[string]*mask{}
+		}
+		child, ok := m.children[s]
+		if !ok {
+			child = &mask{}
+			m.children[s] = child
+		}
+		m = child
+	}
+	m.all, m.children = true, nil
+}
+
+// apply returns v with only the allowed documents, and false if v is a scalar
+// that is not allowed as a whole.
+func (m *mask) apply(v any) (any, bool) {
+	if m.all {
+		return v, true
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(m.children))
+		for k, child := range m.children {
+			if x, ok := v[k]; ok {
+				if x, ok := child.apply(x); ok {
+					obj[k] = x
+				}
+			}
+		}
+		return obj, true
+	case []any:
+		arr := make([]any, 0, len(m.children))
+		for i, x := range v {
+			if child, ok := m.children[strconv.Itoa(i)]; ok {
+				if x, ok := child.apply(x); ok {
+					arr = append(arr, x)
+				}
+			}
+		}
+		return arr, true
+	}
+	return nil, false
+}
+
+// ReadString reads the document at path and returns it as a string. If the
+// document is not a string, an error with the TypeMismatchErr code is returned.
+func ReadString(ctx context.Context, store Store, txn Transaction, path Path) (string, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", typeMismatchError(path, "string", v)
+	}
+	return s, nil
+}
+
+// ReadInt64 reads the document at path and returns it as an int64. If the
+// document is not an integral number representable as an int64, an error with
+// the TypeMismatchErr code is returned.
+func ReadInt64(ctx context.Context, store Store, txn Transaction, path Path) (int64, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := toInt64(v)
+	if !ok {
+		return 0, typeMismatchError(path, "integer", v)
+	}
+	return n, nil
+}
+
+// ReadBool reads the document at path and returns it as a bool. If the
+// document is not a boolean, an error with the TypeMismatchErr code is returned.
+func ReadBool(ctx context.Context, store Store, txn Transaction, path Path) (bool, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, typeMismatchError(path, "boolean", v)
+	}
+	return b, nil
+}
+
+// ReadMap reads the document at path and returns it as an object. If the
+// document is not an object, an error with the TypeMismatchErr code is
+// returned. The result may share structure with the store and must not be
+// modified.
+func ReadMap(ctx context.Context, store Store, txn Transaction, path Path) (map[string]any, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, typeMismatchError(path, "object", v)
+	}
+	return m, nil
+}
+
+// ReadSlice reads the document at path and returns it as an array. If the
+// document is not an array, an error with the TypeMismatchErr code is
+// returned. The result may share structure with the store and must not be
+// modified.
+func ReadSlice(ctx context.Context, store Store, txn Transaction, path Path) ([]any, error) {
+	v, err := ReadRaw(ctx, store, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.([]any)
+	if !ok {
+		return nil, typeMismatchError(path, "array", v)
+	}
+	return s, nil
+}
+
+// ListKeys returns the immediate children of the document at path: the sorted
+// keys of an object, or the indices "0" to "n-1" of an array. Values are not
+// converted. If the document is neither an object nor an array, an error with
+// the TypeMismatchErr code is returned.
+func ListKeys(ctx context.Context, store Store, txn Transaction, path Path) ([]string, error) {
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return util.KeysSorted(v), nil
+	case []any:
+		return indexKeys(len(v)), nil
+	case ast.Object:
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.Keys() {
+			// Keys of stored documents are always strings.
+			if s, ok := k.Value.(ast.String); ok {
+				keys = append(keys, string(s))
+			}
+		}
+		slices.Sort(keys)
+		return keys, nil
+	case *ast.Array:
+		return indexKeys(v.Len()), nil
+	}
+
+	return nil, typeMismatchError(path, "object or array", v)
+}
+
+func indexKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	return keys
+}
+
+// IsNull returns true if v and err, as returned by a Read, denote a document
+// that exists and is explicitly set to null. It returns false for missing
+// documents, which are reported through a NotFoundErr.
+func IsNull(v any, err error) bool {
+	if err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, ast.Null:
+		return true
+	}
+	return false
+}
+
+// typeName returns the JSON type name of a document.
+func typeName(v any) string {
+	if k := KindOf(v); k != KindInvalid {
+		return k.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ReadRaw reads the document at path and converts AST values returned by
+// stores configured to hold AST data into their native Go representation. If
+// the store implements RawReader, its ReadRaw method is used instead.
+func ReadRaw(ctx context.Context, store Store, txn Transaction, path Path) (any, error) {
+	if r, ok := store.(RawReader); ok {
+		return r.ReadRaw(ctx, txn, path)
+	}
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return nil, err
+	}
+	if av, ok := v.(ast.Value); ok {
+		return ast.JSON(av)
+	}
+	return v, nil
+}
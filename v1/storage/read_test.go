@@ -0,0 +1,346 @@
+package storage_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestReadToDepth(t *testing.T) {
+	data := `{"a": {"b": {"c": {"d": {"e": 1}}}, "xs": [[1, 2], 3]}, "s": "x"}`
+
+	tests := []struct {
+		note  string
+		path  string
+		depth int
+		exp   string
+	}{
+		{
+			note:  "depth 2",
+			path:  "/",
+			depth: 2,
+			exp:   `{"a": {"b": {"type": "object", "size": 1}, "xs": {"type": "array", "size": 2}}, "s": "x"}`,
+		},
+		{
+			note:  "depth 0",
+			path:  "/a",
+			depth: 0,
+			exp:   `{"type": "object", "size": 2}`,
+		},
+		{
+			note:  "nested path",
+			path:  "/a/xs",
+			depth: 1,
+			exp:   `[{"type": "array", "size": 2}, 3]`,
+		},
+		{
+			note:  "scalar",
+			path:  "/s",
+			depth: 0,
+			exp:   `"x"`,
+		},
+		{
+			note:  "unlimited",
+			path:  "/a/b",
+			depth: -1,
+			exp:   `{"c": {"d": {"e": 1}}}`,
+		},
+	}
+
+	for _, astValues := range []bool{false, true} {
+		store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+
+		for _, tc := range tests {
+			t.Run(tc.note, func(t *testing.T) {
+				ctx := t.Context()
+				txn := storage.NewTransactionOrDie(ctx, store)
+				defer store.Abort(ctx, txn)
+
+				act, err := storage.ReadToDepth(ctx, store, txn, storage.MustParsePath(tc.path), tc.depth)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var exp any
+				if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+					t.Fatal(err)
+				}
+
+				if !reflect.DeepEqual(exp, act) {
+					t.Fatalf("expected %v but got %v", exp, act)
+				}
+			})
+		}
+	}
+
+	// The store must not be modified by truncation.
+	ctx := t.Context()
+	store := inmem.NewFromReader(strings.NewReader(data))
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if _, err := storage.ReadToDepth(ctx, store, txn, storage.RootPath, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := store.Read(ctx, txn, storage.MustParsePath("/a/b/c/d/e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != json.Number("1") {
+		t.Fatalf("expected store to be unchanged, got %v", act)
+	}
+}
+
+func TestReadExcluding(t *testing.T) {
+	data := `{"users": {"alice": {"name": "Alice", "password": "secret", "keys": [{"id": 1, "password": "x"}]}}}`
+	exp := `{"alice": {"name": "Alice", "keys": [{"id": 1}]}}`
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			act, err := storage.ReadExcluding(ctx, store, txn, storage.MustParsePath("/users"), []string{"password"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var expV any
+			if err := util.UnmarshalJSON([]byte(exp), &expV); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expV, act) {
+				t.Fatalf("expected %v but got %v", expV, act)
+			}
+
+			// The store still holds the excluded fields.
+			if _, err := store.Read(ctx, txn, storage.MustParsePath("/users/alice/password")); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReadMasked(t *testing.T) {
+	data := `{"users": {"bob": {"name": "Bob", "ssn": "123", "contacts": {"email": "bob@example.com", "phone": "555"}, "tags": ["a", "b", "c"]}}}`
+
+	tests := []struct {
+		note  string
+		allow []string
+		exp   string
+	}{
+		{note: "fields", allow: []string{"/name", "/contacts/email"}, exp: `{"name": "Bob", "contacts": {"email": "bob@example.com"}}`},
+		{note: "array index", allow: []string{"/tags/2", "/tags/0"}, exp: `{"tags": ["a", "c"]}`},
+		{note: "overlapping", allow: []string{"/contacts/phone", "/contacts"}, exp: `{"contacts": {"email": "bob@example.com", "phone": "555"}}`},
+		{note: "missing", allow: []string{"/name/first", "/address"}, exp: `{}`},
+		{note: "root", allow: []string{"/"}, exp: `{"name": "Bob", "ssn": "123", "contacts": {"email": "bob@example.com", "phone": "555"}, "tags": ["a", "b", "c"]}`},
+	}
+
+	for _, astValues := range []bool{false, true} {
+		store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s/ast=%v", tc.note, astValues), func(t *testing.T) {
+				ctx := t.Context()
+				txn := storage.NewTransactionOrDie(ctx, store)
+				defer store.Abort(ctx, txn)
+
+				allow := make([]storage.Path, len(tc.allow))
+				for i, p := range tc.allow {
+					allow[i] = storage.MustParsePath(p)
+				}
+
+				act, err := storage.ReadMasked(ctx, store, txn, storage.MustParsePath("/users/bob"), allow)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var exp any
+				if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(exp, act) {
+					t.Fatalf("expected %v but got %v", exp, act)
+				}
+			})
+		}
+	}
+}
+
+func TestTypedReads(t *testing.T) {
+	data := `{"s": "x", "n": 7, "f": 1.5, "b": true, "m": {"k": "v"}, "a": [1, 2], "z": null}`
+
+	for _, astValues := range []bool{false, true} {
+		store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+
+		tests := []struct {
+			note string
+			path string
+			read func(context.Context, storage.Transaction, storage.Path) (any, error)
+			exp  any
+		}{
+			{
+				note: "string",
+				path: "/s",
+				read: func(ctx context.Context, txn storage.Transaction, p storage.Path) (any, error) {
+					return storage.ReadString(ctx, store, txn, p)
+				},
+				exp: "x",
+			},
+			{
+				note: "int64",
+				path: "/n",
+				read: func(ctx context.Context, txn storage.Transaction, p storage.Path) (any, error) {
+					return storage.ReadInt64(ctx, store, txn, p)
+				},
+				exp: int64(7),
+			},
+			{
+				note: "bool",
+				path: "/b",
+				read: func(ctx context.Context, txn storage.Transaction, p storage.Path) (any, error) {
+					return storage.ReadBool(ctx, store, txn, p)
+				},
+				exp: true,
+			},
+			{
+				note: "map",
+				path: "/m",
+				read: func(ctx context.Context, txn storage.Transaction, p storage.Path) (any, error) {
+					return storage.ReadMap(ctx, store, txn, p)
+				},
+				exp: map[string]any{"k": "v"},
+			},
+			{
+				note: "slice",
+				path: "/a",
+				read: func(ctx context.Context, txn storage.Transaction, p storage.Path) (any, error) {
+					return storage.ReadSlice(ctx, store, txn, p)
+				},
+				exp: []any{json.Number("1"), json.Number("2")},
+			},
+		}
+
+		mismatches := map[string]string{
+			"string": "/n",
+			"int64":  "/f",
+			"bool":   "/z",
+			"map":    "/a",
+			"slice":  "/m",
+		}
+
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s/ast=%v", tc.note, astValues), func(t *testing.T) {
+				ctx := t.Context()
+				txn := storage.NewTransactionOrDie(ctx, store)
+				defer store.Abort(ctx, txn)
+
+				act, err := tc.read(ctx, txn, storage.MustParsePath(tc.path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(tc.exp, act) {
+					t.Fatalf("expected %v but got %v", tc.exp, act)
+				}
+
+				path := storage.MustParsePath(mismatches[tc.note])
+				_, err = tc.read(ctx, txn, path)
+				if !storage.IsTypeMismatch(err) {
+					t.Fatalf("expected type mismatch error but got %v", err)
+				}
+				if !strings.Contains(err.Error(), path.String()) {
+					t.Fatalf("expected error to mention path %v: %v", path, err)
+				}
+
+				if _, err := tc.read(ctx, txn, storage.MustParsePath("/missing")); !storage.IsNotFound(err) {
+					t.Fatalf("expected not found error but got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"a": null, "b": false, "c": {}}`), inmem.OptReturnASTValuesOnRead(astValues))
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			v, err := store.Read(ctx, txn, storage.MustParsePath("/a"))
+			if err != nil {
+				t.Fatalf("expected explicit null to be readable, got %v", err)
+			}
+			if !storage.IsNull(v, err) {
+				t.Fatalf("expected %v to be null", v)
+			}
+
+			v, err = store.Read(ctx, txn, storage.MustParsePath("/missing"))
+			if !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+			if storage.IsNull(v, err) {
+				t.Fatal("expected missing document not to be null")
+			}
+
+			for _, path := range []string{"/b", "/c"} {
+				if v, err := store.Read(ctx, txn, storage.MustParsePath(path)); storage.IsNull(v, err) {
+					t.Fatalf("%s: expected %v not to be null", path, v)
+				}
+			}
+		})
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	data := `{"a": {"z": 1, "b": {"c": 2}, "m": null}, "xs": ["p", "q", "r"], "s": "x"}`
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			tests := map[string][]string{
+				"/":    {"a", "s", "xs"},
+				"/a":   {"b", "m", "z"},
+				"/a/b": {"c"},
+				"/xs":  {"0", "1", "2"},
+			}
+
+			for path, exp := range tests {
+				act, err := storage.ListKeys(ctx, store, txn, storage.MustParsePath(path))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(exp, act) {
+					t.Fatalf("%s: expected %v but got %v", path, exp, act)
+				}
+			}
+
+			_, err := storage.ListKeys(ctx, store, txn, storage.MustParsePath("/s"))
+			if !storage.IsTypeMismatch(err) {
+				t.Fatalf("expected type mismatch error but got %v", err)
+			}
+			if !strings.Contains(err.Error(), "expected object or array but got string") {
+				t.Fatalf("unexpected error message: %v", err)
+			}
+
+			if _, err := storage.ListKeys(ctx, store, txn, storage.MustParsePath("/missing")); !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+		})
+	}
+}
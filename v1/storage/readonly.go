@@ -0,0 +1,94 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+)
+
+// NewReadOnly returns a Store that forwards reads to underlying and rejects
+// every modification with a ReadOnlyErr. Write transactions fail when they
+// are opened, so callers find out before doing any work. Data and policy
+// writes, as well as Truncate, are rejected regardless of the transaction
+// passed in.
+//
+// The underlying store can still be modified directly, e.g., by a replication
+// process, and triggers registered on it keep firing.
+func NewReadOnly(underlying Store) Store {
+	return &readOnlyStore{underlying: underlying}
+}
+
+type readOnlyStore struct {
+	underlying Store
+}
+
+func (s *readOnlyStore) NewTransaction(ctx context.Context, params ...TransactionParams) (Transaction, error) {
+	for _, p := range params {
+		if p.Write {
+			return nil, readOnlyError("write transaction")
+		}
+	}
+	return s.underlying.NewTransaction(ctx, params...)
+}
+
+func (s *readOnlyStore) Read(ctx context.Context, txn Transaction, path Path) (any, error) {
+	return s.underlying.Read(ctx, txn, path)
+}
+
+func (*readOnlyStore) Write(context.Context, Transaction, PatchOp, Path, any) error {
+	return readOnlyError("write")
+}
+
+func (s *readOnlyStore) Commit(ctx context.Context, txn Transaction) error {
+	return s.underlying.Commit(ctx, txn)
+}
+
+func (*readOnlyStore) Truncate(context.Context, Transaction, TransactionParams, Iterator) error {
+	return readOnlyError("truncate")
+}
+
+func (s *readOnlyStore) Abort(ctx context.Context, txn Transaction) {
+	s.underlying.Abort(ctx, txn)
+}
+
+func (s *readOnlyStore) ListPolicies(ctx context.Context, txn Transaction) ([]string, error) {
+	return s.underlying.ListPolicies(ctx, txn)
+}
+
+func (s *readOnlyStore) GetPolicy(ctx context.Context, txn Transaction, id string) ([]byte, error) {
+	return s.underlying.GetPolicy(ctx, txn, id)
+}
+
+func (*readOnlyStore) UpsertPolicy(context.Context, Transaction, string, []byte) error {
+	return readOnlyError("policy upsert")
+}
+
+func (*readOnlyStore) DeletePolicy(context.Context, Transaction, string) error {
+	return readOnlyError("policy delete")
+}
+
+func (s *readOnlyStore) Register(ctx context.Context, txn Transaction, config TriggerConfig) (TriggerHandle, error) {
+	return s.underlying.Register(ctx, txn, config)
+}
+
+// Close closes the underlying store if it supports being closed.
+func (s *readOnlyStore) Close(ctx context.Context) error {
+	if c, ok := s.underlying.(interface{ Close(context.Context) error }); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+func (s *readOnlyStore) NonEmpty(ctx context.Context, txn Transaction) func([]string) (bool, error) {
+	return NonEmpty(ctx, s.underlying, txn)
+}
+
+func (s *readOnlyStore) ReadRaw(ctx context.Context, txn Transaction, path Path) (any, error) {
+	return ReadRaw(ctx, s.underlying, txn, path)
+}
+
+func (s *readOnlyStore) Kind(ctx context.Context, txn Transaction, path Path) (Kind, error) {
+	return TypeOf(ctx, s.underlying, txn, path)
+}
@@ -0,0 +1,136 @@
+package storage_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestReadOnly(t *testing.T) {
+	ctx := t.Context()
+	underlying := inmem.NewFromReader(strings.NewReader(`{"a": {"b": 1}}`))
+
+	err := storage.Txn(ctx, underlying, storage.WriteParams, func(txn storage.Transaction) error {
+		return underlying.UpsertPolicy(ctx, txn, "test.rego", []byte("package test"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewReadOnly(underlying)
+
+	if _, err := store.NewTransaction(ctx, storage.WriteParams); !storage.IsReadOnly(err) {
+		t.Fatalf("expected read-only error but got %v", err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	act, err := store.Read(ctx, txn, storage.MustParsePath("/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp any
+	if err := util.UnmarshalJSON([]byte(`{"b": 1}`), &exp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %v but got %v", exp, act)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "test.rego" {
+		t.Fatalf("unexpected policies: %v", ids)
+	}
+	if _, err := store.GetPolicy(ctx, txn, "test.rego"); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := map[string]func() error{
+		"write": func() error {
+			return store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/x"), 1)
+		},
+		"upsert policy": func() error {
+			return store.UpsertPolicy(ctx, txn, "x.rego", []byte("package x"))
+		},
+		"delete policy": func() error {
+			return store.DeletePolicy(ctx, txn, "test.rego")
+		},
+		"truncate": func() error {
+			return store.Truncate(ctx, txn, storage.WriteParams, nil)
+		},
+	}
+
+	for name, write := range writes {
+		if err := write(); !storage.IsReadOnly(err) {
+			t.Errorf("%s: expected read-only error but got %v", name, err)
+		}
+	}
+
+	// Nothing reached the underlying store.
+	if _, err := storage.ReadOne(ctx, underlying, storage.MustParsePath("/x")); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got %v", err)
+	}
+}
+
+type closingStore struct {
+	storage.Store
+	closed   bool
+	nonEmpty bool
+	readRaw  bool
+}
+
+func (s *closingStore) Close(context.Context) error {
+	s.closed = true
+	return nil
+}
+
+func (s *closingStore) NonEmpty(context.Context, storage.Transaction) func([]string) (bool, error) {
+	s.nonEmpty = true
+	return func([]string) (bool, error) { return true, nil }
+}
+
+func (s *closingStore) ReadRaw(context.Context, storage.Transaction, storage.Path) (any, error) {
+	s.readRaw = true
+	return "raw", nil
+}
+
+func TestReadOnlyForwardsOptionalInterfaces(t *testing.T) {
+	ctx := t.Context()
+	underlying := &closingStore{Store: inmem.New()}
+	store := storage.NewReadOnly(underlying)
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if ok, err := storage.NonEmpty(ctx, store, txn)([]string{"x"}); err != nil || !ok || !underlying.nonEmpty {
+		t.Fatalf("expected NonEmpty to be forwarded, got %v, %v", ok, err)
+	}
+	if v, err := storage.ReadRaw(ctx, store, txn, storage.RootPath); err != nil || v != "raw" || !underlying.readRaw {
+		t.Fatalf("expected ReadRaw to be forwarded, got %v, %v", v, err)
+	}
+
+	c, ok := store.(interface{ Close(context.Context) error })
+	if !ok {
+		t.Fatal("expected read-only store to implement Close")
+	}
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !underlying.closed {
+		t.Fatal("expected underlying store to be closed")
+	}
+
+	// Stores that cannot be closed are left alone.
+	if err := storage.NewReadOnly(inmem.New()).(interface{ Close(context.Context) error }).Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
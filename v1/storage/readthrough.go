@@ -0,0 +1,336 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewReadThrough returns a Store that serves reads from cache and falls back to
+// origin for documents that were not fetched in the last ttl. Documents read
+// from origin are written to cache at the same path before being returned.
+//
+// Writes go to both stores: they are made in origin as part of the
+// transaction and applied to cache once it commits. If a write cannot be
+// applied to cache, cached documents overlapping any of the written paths are
+// invalidated instead, so that the next read fetches them from origin again.
+// Policy operations and triggers go to origin only.
+//
+// Documents read in a transaction that started before another transaction
+// committed are not cached, as they may predate its writes.
+//
+// Reads served from cache are not part of the snapshot of the transaction
+// they are performed in. Callers that need snapshot isolation across reads
+// should use origin directly.
+func NewReadThrough(origin, cache Store, ttl time.Duration) Store {
+	return &readThroughStore{
+		origin:  origin,
+		cache:   cache,
+		ttl:     ttl,
+		fetched: map[string]readThroughEntry{},
+	}
+}
+
+type readThroughStore struct {
+	origin Store
+	cache  Store
+	ttl    time.Duration
+
+	// mu guards fetched and gen, and serializes writes to cache.
+	mu      sync.Mutex
+	fetched map[string]readThroughEntry // keyed by path string
+	gen     uint64                      // incremented on every commit
+}
+
+type readThroughEntry struct {
+	path Path
+	at   time.Time
+}
+
+type readThroughTxn struct {
+	underlying Transaction
+	gen        uint64 // store generation when the transaction started
+	writes     []readThroughWrite
+	truncated  bool
+}
+
+type readThroughWrite struct {
+	op    PatchOp
+	path  Path
+	value any
+}
+
+// written returns true if the transaction modified path or a document
+// overlapping it.
+func (txn *readThroughTxn) written(path Path) bool {
+	if txn.truncated {
+		return true
+	}
+	for _, w := range txn.writes {
+		if path.HasPrefix(w.path) || w.path.HasPrefix(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (txn *readThroughTxn) ID() uint64 {
+	return txn.underlying.ID()
+}
+
+func (s *readThroughStore) NewTransaction(ctx context.Context, params ...TransactionParams) (Transaction, error) {
+	// Read the generation first, so that the origin snapshot is at least as
+	// recent as the commits it accounts for.
+	s.mu.Lock()
+	gen := s.gen
+	s.mu.Unlock()
+
+	txn, err := s.origin.NewTransaction(ctx, params...)
+	if err != nil {
+		return nil, err
+	}
+	return &readThroughTxn{underlying: txn, gen: gen}, nil
+}
+
+func (s *readThroughStore) Read(ctx context.Context, txn Transaction, path Path) (any, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Documents modified in this transaction must be read from origin, and
+	// must not be cached before the transaction commits.
+	if underlying.written(path) {
+		return s.origin.Read(ctx, underlying.underlying, path)
+	}
+
+	if s.cached(ctx, path) {
+		if v, err := ReadOne(ctx, s.cache, path); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := s.origin.Read(ctx, underlying.underlying, path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.populate(ctx, underlying.gen, path, v)
+
+	return v, nil
+}
+
+func (s *readThroughStore) Write(ctx context.Context, txn Transaction, op PatchOp, path Path, value any) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if err := s.origin.Write(ctx, underlying.underlying, op, path, value); err != nil {
+		return err
+	}
+	underlying.writes = append(underlying.writes, readThroughWrite{op: op, path: path, value: value})
+	return nil
+}
+
+func (s *readThroughStore) Commit(ctx context.Context, txn Transaction) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if err := s.origin.Commit(ctx, underlying.underlying); err != nil {
+		return err
+	}
+	s.commit(ctx, underlying)
+	return nil
+}
+
+func (s *readThroughStore) Truncate(ctx context.Context, txn Transaction, params TransactionParams, it Iterator) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if err := s.origin.Truncate(ctx, underlying.underlying, params, it); err != nil {
+		return err
+	}
+	underlying.truncated = true
+	return nil
+}
+
+func (s *readThroughStore) Abort(ctx context.Context, txn Transaction) {
+	if underlying, err := s.underlying(txn); err == nil {
+		s.origin.Abort(ctx, underlying.underlying)
+	}
+}
+
+func (s *readThroughStore) ListPolicies(ctx context.Context, txn Transaction) ([]string, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return s.origin.ListPolicies(ctx, underlying.underlying)
+}
+
+func (s *readThroughStore) GetPolicy(ctx context.Context, txn Transaction, id string) ([]byte, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return s.origin.GetPolicy(ctx, underlying.underlying, id)
+}
+
+func (s *readThroughStore) UpsertPolicy(ctx context.Context, txn Transaction, id string, bs []byte) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	return s.origin.UpsertPolicy(ctx, underlying.underlying, id, bs)
+}
+
+func (s *readThroughStore) DeletePolicy(ctx context.Context, txn Transaction, id string) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	return s.origin.DeletePolicy(ctx, underlying.underlying, id)
+}
+
+func (s *readThroughStore) Register(ctx context.Context, txn Transaction, config TriggerConfig) (TriggerHandle, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return s.origin.Register(ctx, underlying.underlying, config)
+}
+
+func (*readThroughStore) underlying(txn Transaction) (*readThroughTxn, error) {
+	underlying, ok := txn.(*readThroughTxn)
+	if !ok {
+		return nil, &Error{
+			Code:    InvalidTransactionErr,
+			Message: "unexpected transaction type",
+		}
+	}
+	return underlying, nil
+}
+
+// cached returns true if path, or one of its prefixes, was fetched from
+// origin within the last ttl. Expired documents found along path are removed
+// from the cache.
+func (s *readThroughStore) cached(ctx context.Context, path Path) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []Path
+	for i := len(path); i >= 0; i-- {
+		e, ok := s.fetched[path[:i].String()]
+		if !ok {
+			continue
+		}
+		if time.Since(e.at) < s.ttl {
+			return true
+		}
+		expired = append(expired, e.path)
+	}
+
+	if len(expired) > 0 {
+		s.evict(ctx, expired)
+	}
+	return false
+}
+
+// evict removes the documents at paths, and everything fetched under them,
+// from the cache. It must be called with mu held.
+func (s *readThroughStore) evict(ctx context.Context, paths []Path) {
+	for k, e := range s.fetched {
+		for _, p := range paths {
+			if e.path.HasPrefix(p) {
+				delete(s.fetched, k)
+				break
+			}
+		}
+	}
+
+	// Removal is best-effort: documents that remain in the cache are not
+	// served, as they are no longer recorded as fetched.
+	_ = Txn(ctx, s.cache, WriteParams, func(txn Transaction) error {
+		for _, p := range paths {
+			var err error
+			if len(p) == 0 {
+				err = s.cache.Write(ctx, txn, AddOp, p, map[string]any{})
+			} else {
+				err = s.cache.Write(ctx, txn, RemoveOp, p, nil)
+			}
+			if err != nil && !IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// populate writes value, read in a transaction that started at generation
+// gen, to the cache. Nothing is cached if a transaction has committed since, as
+// value may predate its writes. The cache is best-effort: if the write fails,
+// the document is simply fetched from origin again on the next read.
+func (s *readThroughStore) populate(ctx context.Context, gen uint64, path Path, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gen != gen {
+		return
+	}
+
+	err := Txn(ctx, s.cache, WriteParams, func(txn Transaction) error {
+		if len(path) > 0 {
+			if err := MakeDir(ctx, s.cache, txn, path[:len(path)-1]); err != nil {
+				return err
+			}
+		}
+		return s.cache.Write(ctx, txn, AddOp, path, value)
+	})
+	if err != nil {
+		return
+	}
+
+	s.fetched[path.String()] = readThroughEntry{path: path, at: time.Now()}
+}
+
+// commit applies the writes of a committed transaction to the cache. If that
+// fails, cached documents overlapping any of the written paths are forgotten.
+func (s *readThroughStore) commit(ctx context.Context, txn *readThroughTxn) {
+	if !txn.truncated && len(txn.writes) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gen++
+
+	if txn.truncated {
+		clear(s.fetched)
+		return
+	}
+
+	err := Txn(ctx, s.cache, WriteParams, func(ctxn Transaction) error {
+		for _, w := range txn.writes {
+			if err := s.cache.Write(ctx, ctxn, w.op, w.path, w.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	for k, e := range s.fetched {
+		if txn.written(e.path) {
+			delete(s.fetched, k)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package storage_test
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+)
+
+type countingStore struct {
+	storage.Store
+	reads atomic.Int64
+}
+
+func (s *countingStore) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+	s.reads.Add(1)
+	time.Sleep(time.Millisecond) // simulate a slow origin
+	return s.Store.Read(ctx, txn, path)
+}
+
+func TestReadThrough(t *testing.T) {
+	ctx := t.Context()
+	origin := &countingStore{Store: inmem.NewFromReader(strings.NewReader(`{"users": {"alice": {"role": "admin"}}}`))}
+	store := storage.NewReadThrough(origin, inmem.New(), time.Hour)
+
+	read := func(path string) any {
+		t.Helper()
+		v, err := storage.ReadOne(ctx, store, storage.MustParsePath(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	read("/users")
+	read("/users")
+	read("/users/alice/role")
+
+	if n := origin.reads.Load(); n != 1 {
+		t.Fatalf("expected 1 read from origin but got %d", n)
+	}
+
+	// Writes go to both origin and the cache.
+	if err := storage.WriteOne(ctx, store, storage.ReplaceOp, storage.MustParsePath("/users/alice/role"), "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := read("/users/alice/role"); v != "dev" {
+		t.Fatalf("expected updated value but got %v", v)
+	}
+	if n := origin.reads.Load(); n != 1 {
+		t.Fatalf("expected 1 read from origin but got %d", n)
+	}
+
+	// Writes that cannot be applied to the cache invalidate it.
+	if err := storage.WriteOne(ctx, origin.Store, storage.AddOp, storage.MustParsePath("/users/bob"), map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/users/bob/role"), "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := read("/users/bob/role"); v != "dev" {
+		t.Fatalf("expected updated value but got %v", v)
+	}
+	if n := origin.reads.Load(); n != 2 {
+		t.Fatalf("expected 2 reads from origin but got %d", n)
+	}
+
+	// Missing documents are not cached.
+	for range 2 {
+		if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/missing")); !storage.IsNotFound(err) {
+			t.Fatalf("expected not found error but got %v", err)
+		}
+	}
+	if n := origin.reads.Load(); n != 4 {
+		t.Fatalf("expected 4 reads from origin but got %d", n)
+	}
+}
+
+func TestReadThroughExpiry(t *testing.T) {
+	ctx := t.Context()
+	origin := &countingStore{Store: inmem.NewFromReader(strings.NewReader(`{"a": 1}`))}
+	store := storage.NewReadThrough(origin, inmem.New(), time.Nanosecond)
+
+	for range 2 {
+		if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/a")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := origin.reads.Load(); n != 2 {
+		t.Fatalf("expected expired entries to be fetched again, got %d reads", n)
+	}
+}
+
+// snapshotStore is an origin whose read transactions see the documents as of
+// the time they started without blocking commits, e.g., like a remote store.
+// Only top-level documents are supported.
+type snapshotStore struct {
+	storage.Store
+	mu   sync.Mutex
+	data map[string]any
+}
+
+type snapshotTxn struct {
+	data map[string]any
+}
+
+func (*snapshotTxn) ID() uint64 { return 0 }
+
+func (s *snapshotStore) NewTransaction(context.Context, ...storage.TransactionParams) (storage.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &snapshotTxn{data: maps.Clone(s.data)}, nil
+}
+
+func (*snapshotStore) Read(_ context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+	v, ok := txn.(*snapshotTxn).data[path[0]]
+	if !ok {
+		return nil, &storage.Error{Code: storage.NotFoundErr}
+	}
+	return v, nil
+}
+
+func (*snapshotStore) Write(_ context.Context, txn storage.Transaction, _ storage.PatchOp, path storage.Path, value any) error {
+	txn.(*snapshotTxn).data[path[0]] = value
+	return nil
+}
+
+func (s *snapshotStore) Commit(_ context.Context, txn storage.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = txn.(*snapshotTxn).data
+	return nil
+}
+
+func (*snapshotStore) Abort(context.Context, storage.Transaction) {}
+
+func TestReadThroughStaleSnapshot(t *testing.T) {
+	ctx := t.Context()
+	path := storage.MustParsePath("/a")
+	store := storage.NewReadThrough(&snapshotStore{data: map[string]any{"a": "old"}}, inmem.New(), time.Hour)
+
+	// This transaction keeps reading the document as it was before the write
+	// below is committed.
+	txn := storage.NewTransactionOrDie(ctx, store)
+
+	if err := storage.WriteOne(ctx, store, storage.ReplaceOp, path, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "old" {
+		t.Fatalf("expected snapshot value but got %v", v)
+	}
+	store.Abort(ctx, txn)
+
+	v, err = storage.ReadOne(ctx, store, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "new" {
+		t.Fatalf("expected stale snapshot not to be cached, got %v", v)
+	}
+}
+
+func TestReadThroughEvictsExpired(t *testing.T) {
+	ctx := t.Context()
+	origin := inmem.NewFromReader(strings.NewReader(`{"a": {"b": 1, "c": 2}}`))
+	cache := inmem.New()
+	store := storage.NewReadThrough(origin, cache, 10*time.Millisecond)
+
+	if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.ReadOne(ctx, cache, storage.MustParsePath("/a/c")); err != nil {
+		t.Fatalf("expected /a to be cached: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	// Reading under the expired document removes it from the cache, and only
+	// caches the document read.
+	if _, err := storage.ReadOne(ctx, store, storage.MustParsePath("/a/b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.ReadOne(ctx, cache, storage.MustParsePath("/a/c")); !storage.IsNotFound(err) {
+		t.Fatalf("expected expired document to be removed from the cache, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// RemovePrefix removes the document at prefix, including everything nested
+// under it, within txn. It returns 1 if a document was removed, and 0 without
+// error if there was no document at prefix, like RemoveChildren returns the
+// number of children removed. The root document cannot be removed; use
+// RemoveChildren to clear it.
+func RemovePrefix(ctx context.Context, store Store, txn Transaction, prefix Path) (int, error) {
+	if err := store.Write(ctx, txn, RemoveOp, prefix, nil); err != nil {
+		if IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return 1, nil
+}
+
+// RemoveChildren removes all immediate children of the object or array at
+// path within txn, leaving an empty object or array in place. It returns the
+// number of children removed. If the document at path is neither an object nor
+// an array, an error with the TypeMismatchErr code is returned.
+func RemoveChildren(ctx context.Context, store Store, txn Transaction, path Path) (int, error) {
+	if len(path) == 0 {
+		return removeRootChildren(ctx, store, txn)
+	}
+
+	v, err := store.Read(ctx, txn, path)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	var empty any
+	switch v := v.(type) {
+	case map[string]any:
+		n, empty = len(v), map[string]any{}
+	case ast.Object:
+		n, empty = v.Len(), map[string]any{}
+	case []any:
+		n, empty = len(v), []any{}
+	case *ast.Array:
+		n, empty = v.Len(), []any{}
+	default:
+		return 0, typeMismatchError(path, "object or array", v)
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+	// Replace the document in one write, instead of removing each child.
+	if err := store.Write(ctx, txn, ReplaceOp, path, empty); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// removeRootChildren removes the top-level documents one by one, as the root
+// document itself cannot be replaced in every store.
+func removeRootChildren(ctx context.Context, store Store, txn Transaction) (int, error) {
+	keys, err := ListKeys(ctx, store, txn, RootPath)
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range keys {
+		if err := store.Write(ctx, txn, RemoveOp, Path{k}, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
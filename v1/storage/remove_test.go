@@ -0,0 +1,103 @@
+package storage_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+func TestRemovePrefix(t *testing.T) {
+	data := `{"tenants": {"acme": {"users": {"a": 1}}, "globex": {"users": {}}}, "xs": [1, 2, 3], "s": "x"}`
+
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(data), inmem.OptReturnASTValuesOnRead(astValues))
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				for path, exp := range map[string]int{"/tenants/acme": 1, "/tenants/missing": 0} {
+					n, err := storage.RemovePrefix(ctx, store, txn, storage.MustParsePath(path))
+					if err != nil {
+						return err
+					}
+					if n != exp {
+						t.Errorf("%s: expected %d removed but got %d", path, exp, n)
+					}
+				}
+
+				n, err := storage.RemoveChildren(ctx, store, txn, storage.MustParsePath("/xs"))
+				if err != nil {
+					return err
+				}
+				if n != 3 {
+					t.Errorf("expected 3 array elements removed but got %d", n)
+				}
+
+				if _, err := storage.RemoveChildren(ctx, store, txn, storage.MustParsePath("/s")); !storage.IsTypeMismatch(err) {
+					t.Errorf("expected type mismatch error but got %v", err)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := storage.ReadOne(ctx, store, storage.RootPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var exp any
+			if err := util.UnmarshalJSON([]byte(`{"tenants": {"globex": {"users": {}}}, "xs": [], "s": "x"}`), &exp); err != nil {
+				t.Fatal(err)
+			}
+
+			if ast.MustInterfaceToValue(exp).Compare(ast.MustInterfaceToValue(act)) != 0 {
+				t.Fatalf("expected %v but got %v", exp, act)
+			}
+		})
+	}
+}
+
+func TestRemoveChildrenObjectAndRoot(t *testing.T) {
+	for _, astValues := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ast=%v", astValues), func(t *testing.T) {
+			ctx := t.Context()
+			store := inmem.NewFromReaderWithOpts(strings.NewReader(`{"a": {"x": 1, "y": {"z": 2}}, "b": "s"}`), inmem.OptReturnASTValuesOnRead(astValues))
+
+			for _, tc := range []struct {
+				path string
+				n    int
+				exp  string
+			}{
+				{path: "/a", n: 2, exp: `{"a": {}, "b": "s"}`},
+				{path: "/", n: 2, exp: `{}`},
+			} {
+				var n int
+				err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) (err error) {
+					n, err = storage.RemoveChildren(ctx, store, txn, storage.MustParsePath(tc.path))
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != tc.n {
+					t.Errorf("%s: expected %d children removed but got %d", tc.path, tc.n, n)
+				}
+
+				act, err := storage.ReadOne(ctx, store, storage.RootPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exp := util.MustUnmarshalJSON([]byte(tc.exp)); ast.MustInterfaceToValue(exp).Compare(ast.MustInterfaceToValue(act)) != 0 {
+					t.Fatalf("%s: expected %v but got %v", tc.path, exp, act)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2026 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"cmp"
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+// NewStructStore returns a read-only Store that serves the Go value v as the
+// root document. Paths are looked up in v via reflection on every read, so
+// changes made to v by the caller are visible to subsequent reads, and only
+// the document read is encoded, with encoding/json. All writes, policy
+// mutations and trigger registrations are rejected.
+//
+// Callers are responsible for synchronizing any modification of v with reads
+// performed through the store.
+func NewStructStore(v any) Store {
+	return &structStore{root: reflect.ValueOf(v)}
+}
+
+type structStore struct {
+	WritesNotSupported
+	PolicyNotSupported
+	TriggersNotSupported
+	root reflect.Value
+	xid  uint64
+}
+
+type structTxn struct {
+	xid uint64
+}
+
+func (txn *structTxn) ID() uint64 {
+	return txn.xid
+}
+
+func (s *structStore) NewTransaction(_ context.Context, params ...TransactionParams) (Transaction, error) {
+	if len(params) > 0 && params[0].Write {
+		return nil, writesNotSupportedError()
+	}
+	return &structTxn{xid: atomic.AddUint64(&s.xid, 1)}, nil
+}
+
+func (s *structStore) Read(_ context.Context, _ Transaction, path Path) (any, error) {
+	node := s.root
+	for i := range path {
+		next, found, encode := reflectLookup(node, path[i])
+		if encode {
+			// The document under node depends on rules of encoding/json,
+			// so the rest of the path is looked up in the encoded document.
+			doc, err := encodeJSON(node)
+			if err != nil {
+				return nil, err
+			}
+			if doc, found = lookupJSON(doc, path[i:]); !found {
+				return nil, notFoundError(path)
+			}
+			return doc, nil
+		}
+		if !found {
+			return nil, notFoundError(path)
+		}
+		node = next
+	}
+	return encodeJSON(node)
+}
+
+func (*structStore) Commit(context.Context, Transaction) error {
+	return nil
+}
+
+func (*structStore) Abort(context.Context, Transaction) {}
+
+func (*structStore) Truncate(context.Context, Transaction, TransactionParams, Iterator) error {
+	return writesNotSupportedError()
+}
+
+// encodeJSON returns the document encoding/json produces for v.
+func encodeJSON(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	// Encode addressable values through a pointer, so that methods with
+	// pointer receivers are used as when encoding the enclosing value.
+	x := v.Interface()
+	if v.CanAddr() {
+		x = v.Addr().Interface()
+	}
+	bs, err := json.Marshal(x)
+	if err != nil {
+		return nil, &Error{Code: InternalErr, Message: err.Error()}
+	}
+	var doc any
+	return doc, util.UnmarshalJSON(bs, &doc)
+}
+
+func reflectIndirect(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+// reflectLookup returns the value under key in v, and whether it was found.
+// If finding it requires rules of encoding/json beyond field names, e.g., for
+// Marshaler implementations or fields with options such as omitempty, it
+// reports that v must be encoded instead.
+func reflectLookup(v reflect.Value, key string) (next reflect.Value, found, encode bool) {
+	v, ok := reflectIndirect(v)
+	if !ok {
+		return reflect.Value{}, false, false
+	}
+	if isMarshaler(v) {
+		return reflect.Value{}, false, true
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range cachedStructFields(v.Type()) {
+			if f.name == key {
+				if f.options {
+					return reflect.Value{}, false, true
+				}
+				fv, ok := fieldByIndex(v, f.index)
+				return fv, ok, false
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false, true
+		}
+		elem := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+		return elem, elem.IsValid(), false
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return reflect.Value{}, false, true
+		}
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= v.Len() {
+			return reflect.Value{}, false, false
+		}
+		return v.Index(idx), true, false
+	}
+
+	return reflect.Value{}, false, false
+}
+
+// lookupJSON returns the value at path in a document made of map[string]any
+// and []any values.
+func lookupJSON(doc any, path Path) (any, bool) {
+	for _, key := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			var ok bool
+			if doc, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			doc = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func isMarshaler(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+type structField struct {
+	name    string
+	index   []int
+	tagged  bool
+	options bool // e.g., omitempty or string
+}
+
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+func cachedStructFields(t reflect.Type) []structField {
+	if fs, ok := structFieldCache.Load(t); ok {
+		return fs.([]structField)
+	}
+	fs, _ := structFieldCache.LoadOrStore(t, structFields(t))
+	return fs.([]structField)
+}
+
+// structFields returns the names of the fields of t that encoding/json
+// encodes, following its rules: fields of untagged embedded structs are
+// promoted, and of several fields with the same name only the shallowest one
+// is kept, preferring a tagged one. If that still leaves more than one, none
+// of them is encoded.
+func structFields(t reflect.Type) []structField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []structField
+	var count, nextCount map[reflect.Type]int
+	current, next := []embedded{}, []embedded{{typ: t}}
+	visited := map[reflect.Type]bool{}
+
+	// Embedded structs are visited breadth first, one depth at a time.
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := range e.typ.NumField() {
+				sf := e.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := append(slices.Clip(e.index), i)
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					f := structField{name: name, index: index, tagged: name != "", options: opts != ""}
+					if f.name == "" {
+						f.name = sf.Name
+					}
+					fields = append(fields, f)
+					if count[e.typ] > 1 {
+						// The same struct is embedded more than once at this
+						// depth, so its fields conflict with each other.
+						fields = append(fields, f)
+					}
+					continue
+				}
+
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, embedded{typ: ft, index: index})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(fields, func(a, b structField) int {
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(len(a.index), len(b.index)); c != 0 {
+			return c
+		}
+		if a.tagged != b.tagged {
+			if a.tagged {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.index, b.index)
+	})
+
+	out := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		// The first field of each name dominates, unless the next one is
+		// at the same depth and equally tagged.
+		if j-i == 1 || len(fields[i].index) != len(fields[i+1].index) || fields[i].tagged != fields[i+1].tagged {
+			out = append(out, fields[i])
+		}
+		i = j
+	}
+
+	slices.SortFunc(out, func(a, b structField) int {
+		return slices.Compare(a.index, b.index)
+	})
+	return out
+}
+
+// isValidTag reports whether s may be used as a field name, as in encoding/json.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but reports false instead of
+// panicking when traversing a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			var ok bool
+			if v, ok = reflectIndirect(v); !ok {
+				return reflect.Value{}, false
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
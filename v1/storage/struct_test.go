@@ -0,0 +1,250 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/storage"
+	"github.com/open-policy-agent/opa/v1/util"
+)
+
+type structStoreAddress struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type structStoreUser struct {
+	Name     string              `json:"name"`
+	Age      int                 `json:"age"`
+	Address  *structStoreAddress `json:"address"`
+	Tags     []string            `json:"tags"`
+	Password string              `json:"-"`
+	internal string
+}
+
+type structStoreConfig struct {
+	Users   []structStoreUser `json:"users"`
+	Enabled bool              `json:"enabled"`
+	Limits  map[string]int    `json:"limits"`
+	Version string
+}
+
+func TestStructStoreRead(t *testing.T) {
+	cfg := &structStoreConfig{
+		Users: []structStoreUser{
+			{
+				Name:     "alice",
+				Age:      30,
+				Address:  &structStoreAddress{City: "Paris"},
+				Tags:     []string{"admin", "dev"},
+				Password: "secret",
+				internal: "x",
+			},
+		},
+		Enabled: true,
+		Limits:  map[string]int{"rps": 10},
+		Version: "v1",
+	}
+
+	store := storage.NewStructStore(cfg)
+	ctx := t.Context()
+
+	tests := []struct {
+		path string
+		exp  any
+	}{
+		{"/enabled", true},
+		{"/Version", "v1"},
+		{"/limits/rps", json.Number("10")},
+		{"/users/0/name", "alice"},
+		{"/users/0/age", json.Number("30")},
+		{"/users/0/address/city", "Paris"},
+		{"/users/0/tags/1", "dev"},
+		{"/users/0/address", map[string]any{"city": "Paris"}},
+		{"/users/0", map[string]any{
+			"name":    "alice",
+			"age":     json.Number("30"),
+			"address": map[string]any{"city": "Paris"},
+			"tags":    []any{"admin", "dev"},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			act, err := storage.ReadOne(ctx, store, storage.MustParsePath(tc.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Fatalf("expected %v but got %v", tc.exp, act)
+			}
+		})
+	}
+
+	for _, path := range []string{"/missing", "/users/1", "/users/0/Password", "/users/0/internal", "/users/0/name/x"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := storage.ReadOne(ctx, store, storage.MustParsePath(path))
+			if !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+		})
+	}
+
+	// Reads reflect the current state of the underlying value.
+	cfg.Users[0].Name = "bob"
+
+	act, err := storage.ReadOne(ctx, store, storage.MustParsePath("/users/0/name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != "bob" {
+		t.Fatalf("expected bob but got %v", act)
+	}
+}
+
+type structStoreMarshaler struct{}
+
+func (structStoreMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a": {"b": 1}}`), nil
+}
+
+type structStoreInner struct {
+	X int `json:"x"`
+}
+
+type structStoreOmit struct {
+	Inner    structStoreInner `json:"inner,omitempty"`
+	Empty    []string         `json:"empty,omitempty"`
+	EmptyMap map[string]int   `json:"empty_map,omitempty"`
+	Zero     time.Time        `json:"zero,omitzero"`
+	Num      int              `json:"num,string"`
+}
+
+type structStoreEmbedA struct {
+	Name string
+	ID   int
+}
+
+type structStoreEmbedB struct {
+	Name string
+	ID   int `json:"ID"`
+}
+
+type structStoreDominance struct {
+	structStoreEmbedA
+	structStoreEmbedB
+}
+
+type structStoreJSON struct {
+	Omit      structStoreOmit      `json:"omit"`
+	Marshaler structStoreMarshaler `json:"marshaler"`
+	Ints      map[int]string       `json:"ints"`
+	Dominance structStoreDominance `json:"dominance"`
+}
+
+func TestStructStoreMatchesEncodingJSON(t *testing.T) {
+	v := &structStoreJSON{
+		Omit:      structStoreOmit{Empty: []string{}, EmptyMap: map[string]int{}, Num: 7},
+		Ints:      map[int]string{1: "one", -2: "minus two"},
+		Dominance: structStoreDominance{structStoreEmbedA{"a", 1}, structStoreEmbedB{"b", 2}},
+	}
+
+	store := storage.NewStructStore(v)
+	ctx := t.Context()
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exp any
+	if err := util.UnmarshalJSON(bs, &exp); err != nil {
+		t.Fatal(err)
+	}
+
+	act, err := storage.ReadOne(ctx, store, storage.RootPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Fatalf("expected %v but got %v", exp, act)
+	}
+
+	tests := []struct {
+		path string
+		exp  any
+	}{
+		{"/omit/inner/x", json.Number("0")},
+		{"/omit/num", "7"},
+		{"/marshaler/a/b", json.Number("1")},
+		{"/ints/1", "one"},
+		{"/ints/-2", "minus two"},
+		{"/dominance/ID", json.Number("2")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			act, err := storage.ReadOne(ctx, store, storage.MustParsePath(tc.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.exp, act) {
+				t.Fatalf("expected %v but got %v", tc.exp, act)
+			}
+		})
+	}
+
+	for _, path := range []string{"/omit/empty", "/omit/empty_map", "/omit/zero", "/marshaler/x", "/ints/3", "/dominance/Name"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := storage.ReadOne(ctx, store, storage.MustParsePath(path))
+			if !storage.IsNotFound(err) {
+				t.Fatalf("expected not found error but got %v", err)
+			}
+		})
+	}
+}
+
+type structStoreNode struct {
+	Next *structStoreNode `json:"next"`
+}
+
+func TestStructStoreCycle(t *testing.T) {
+	node := &structStoreNode{}
+	node.Next = node
+
+	store := storage.NewStructStore(node)
+	ctx := t.Context()
+
+	if _, err := json.Marshal(node); err == nil {
+		t.Fatal("expected encoding/json to reject the cycle")
+	}
+
+	for _, path := range []string{"/", "/next/next"} {
+		act, err := storage.ReadOne(ctx, store, storage.MustParsePath(path))
+		if err, ok := err.(*storage.Error); !ok || err.Code != storage.InternalErr {
+			t.Fatalf("%v: expected internal error but got %v, %v", path, act, err)
+		}
+	}
+}
+
+func TestStructStoreRejectsWrites(t *testing.T) {
+	store := storage.NewStructStore(&structStoreConfig{})
+	ctx := t.Context()
+
+	if _, err := store.NewTransaction(ctx, storage.WriteParams); err == nil {
+		t.Fatal("expected error opening write transaction")
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/enabled"), true)
+	if err, ok := err.(*storage.Error); !ok || err.Code != storage.WritesNotSupportedErr {
+		t.Fatalf("expected writes not supported error but got %v", err)
+	}
+
+	if err := store.UpsertPolicy(ctx, txn, "x.rego", []byte("package x")); err == nil {
+		t.Fatal("expected error upserting policy")
+	}
+}